@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/place1/wg-embed/pkg/wgembed"
+	"github.com/spf13/cobra"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/helpers/device"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+var (
+	devices = &cobra.Command{
+		Use:   "devices",
+		Short: "Manage devices",
+	}
+
+	reserve = &cobra.Command{
+		Use:   "reserve",
+		Short: "Reserve an address for a device",
+		Long:  "Pin an address to a device name. The device gets the address when it's added and keeps it if it's deleted and added again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunReserve(cmd, args)
+		},
+	}
+
+	unreserve = &cobra.Command{
+		Use:   "unreserve",
+		Short: "Remove the address reservations of a device",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunUnreserve(cmd, args)
+		},
+	}
+
+	reservations = &cobra.Command{
+		Use:   "reservations",
+		Short: "List address reservations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunReservations(cmd, args)
+		},
+	}
+
+	deviceOwner   string
+	deviceName    string
+	deviceAddress string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{reserve, unreserve} {
+		c.Flags().StringVar(&deviceOwner, "owner", "", "email of the device owner")
+		c.Flags().StringVar(&deviceName, "name", "", "device name")
+		c.MarkFlagRequired("owner")
+		c.MarkFlagRequired("name")
+	}
+	reserve.Flags().StringVar(&deviceAddress, "address", "", "address to reserve, e.g. 10.44.0.20")
+	reserve.MarkFlagRequired("address")
+
+	devices.AddCommand(reserve, unreserve, reservations)
+}
+
+func RunReserve(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	user, err := datastore.NewUserStore().FindUserByEmail(ctx, deviceOwner)
+	if err != nil {
+		return fmt.Errorf("no user with email '%s'", deviceOwner)
+	}
+
+	dh := device.NewDeviceHelpers(wgembed.NewNoOpInterface())
+	if _, err := dh.ReserveAddress(ctx, user.Slug, deviceName, deviceAddress); err != nil {
+		util.Logger(ctx).Error("Error reserving address", zap.Error(err))
+		return err
+	}
+
+	fmt.Printf("reserved %s for %s/%s\n", deviceAddress, deviceOwner, deviceName)
+	return nil
+}
+
+func RunUnreserve(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	user, err := datastore.NewUserStore().FindUserByEmail(ctx, deviceOwner)
+	if err != nil {
+		return fmt.Errorf("no user with email '%s'", deviceOwner)
+	}
+
+	dh := device.NewDeviceHelpers(wgembed.NewNoOpInterface())
+	if err := dh.UnreserveAddresses(ctx, user.Slug, deviceName); err != nil {
+		util.Logger(ctx).Error("Error removing reservation", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func RunReservations(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	dh := device.NewDeviceHelpers(wgembed.NewNoOpInterface())
+	items, err := dh.ListReservations(ctx)
+	if err != nil {
+		util.Logger(ctx).Error("Error listing reservations", zap.Error(err))
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tOWNER\tDEVICE")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", item.Address, item.Owner, item.Device)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/place1/wg-embed/pkg/wgembed"
+	"github.com/spf13/cobra"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/firewall"
+	"github.com/waas-app/WaaS/helpers/device"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+var (
+	firewallCmd = &cobra.Command{
+		Use:   "firewall",
+		Short: "Inspect the firewall rules",
+	}
+
+	plan = &cobra.Command{
+		Use:   "plan",
+		Short: "Print the firewall rules without applying them",
+		Long:  "Compute the firewall rules from the current config and device list and print the commands the configured backend would run. Nothing is changed on the host.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunFirewallPlan(cmd, args)
+		},
+	}
+)
+
+func init() {
+	firewallCmd.AddCommand(plan)
+}
+
+func RunFirewallPlan(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	backend, err := firewall.New(config.Spec.VPN.Firewall)
+	if err != nil {
+		return err
+	}
+
+	dh := device.NewDeviceHelpers(wgembed.NewNoOpInterface())
+	rules, err := dh.PlanFirewall(ctx)
+	if err != nil {
+		util.Logger(ctx).Error("Error planning firewall rules", zap.Error(err))
+		return err
+	}
+
+	for _, command := range backend.Commands(rules) {
+		fmt.Println(command)
+	}
+	return nil
+}
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/place1/wg-embed/pkg/wgembed"
@@ -12,6 +19,8 @@ import (
 	"github.com/waas-app/WaaS/config"
 	"github.com/waas-app/WaaS/controller"
 	"github.com/waas-app/WaaS/helpers/device"
+	dnshelpers "github.com/waas-app/WaaS/helpers/dns"
+	"github.com/waas-app/WaaS/helpers/users"
 	"github.com/waas-app/WaaS/infra"
 	"github.com/waas-app/WaaS/infra/auth"
 	"github.com/waas-app/WaaS/infra/middlewares"
@@ -33,6 +42,9 @@ var (
 	wg wgembed.WireGuardInterface
 )
 
+// how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func GetWgInterface() wgembed.WireGuardInterface {
 	return wg
 }
@@ -41,6 +53,8 @@ func RunServe(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	ctx, span := util.Tracer.Start(ctx, "ServeWG")
 	defer span.End()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	var err error
 	defer func() {
 		if err != nil {
@@ -51,6 +65,12 @@ func RunServe(cmd *cobra.Command, args []string) error {
 	serverIP := ip.GetWireGuardServerIP(config.Spec.VPN.CIDR)
 	config.Spec.VPN.AllowedIPs = append(config.Spec.VPN.AllowedIPs, fmt.Sprintf("%s/32", serverIP.IP.String()))
 
+	var serverIPv6 *net.IPNet
+	if config.Spec.VPN.CIDRv6 != "" {
+		serverIPv6 = ip.GetWireGuardServerIP(config.Spec.VPN.CIDRv6)
+		config.Spec.VPN.AllowedIPs = append(config.Spec.VPN.AllowedIPs, fmt.Sprintf("%s/128", serverIPv6.IP.String()))
+	}
+
 	wg = wgembed.NewNoOpInterface()
 
 	// this needs to be run only if wireguard needs to be run.
@@ -78,14 +98,87 @@ func RunServe(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		if err := ip.ConfigureIPTables(ctx, config.Spec.WG.Interface, config.Spec.VPN.GatewayInterface, config.Spec.VPN.CIDR, config.Spec.VPN.AllowedIPs); err != nil {
+		if serverIPv6 != nil {
+			if err := ip.AddInterfaceAddress(config.Spec.WG.Interface, serverIPv6.String()); err != nil {
+				util.Logger(ctx).Error("Error setting WireGuard IPv6 address", zap.Error(err))
+				return err
+			}
+		}
+
+		if err := device.NewDeviceHelpers(wg).ConfigureFirewall(ctx, nil); err != nil {
 			util.Logger(ctx).Error("Error configuring IPTables", zap.Error(err))
 			return err
 		}
+		// the rules of devices created or deleted later
+		device.NewDeviceHelpers(wg).RunFirewallSync(ctx)
+
+		// leave the host's firewall the way we found it,
+		// including when starting the server fails
+		defer func() {
+			teardownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := device.NewDeviceHelpers(wg).TeardownFirewall(teardownCtx); err != nil {
+				util.Logger(ctx).Error("Error removing firewall rules", zap.Error(err))
+			}
+		}()
 	}
 
+	var dns *ip.DNSServer
 	if config.Spec.DNS.Enabled {
-		dns, err := ip.New(ctx, config.Spec.DNS.Upstream)
+		opts := ip.DNSOptions{
+			Upstream:  config.Spec.DNS.Upstream,
+			Race:      config.Spec.DNS.Race,
+			CacheSize: config.Spec.DNS.Cache.Size,
+			Prefetch:  config.Spec.DNS.Cache.Prefetch,
+		}
+
+		if len(config.Spec.DNS.ForwardZones) > 0 {
+			opts.ForwardZones = map[string][]string{}
+			for _, zone := range config.Spec.DNS.ForwardZones {
+				opts.ForwardZones[zone.Zone] = append(opts.ForwardZones[zone.Zone], zone.Upstream...)
+			}
+		}
+
+		if config.Spec.DNS.BindServerIP {
+			opts.Addresses = []string{net.JoinHostPort(serverIP.IP.String(), "53")}
+			if serverIPv6 != nil {
+				opts.Addresses = append(opts.Addresses, net.JoinHostPort(serverIPv6.IP.String(), "53"))
+			}
+		}
+
+		devices := []dnshelpers.DeviceSet{}
+		if config.Spec.DNS.Zone != "" {
+			cidrs := []string{config.Spec.VPN.CIDR}
+			if config.Spec.VPN.CIDRv6 != "" {
+				cidrs = append(cidrs, config.Spec.VPN.CIDRv6)
+			}
+			opts.Zone = ip.NewZone(config.Spec.DNS.Zone, cidrs)
+			devices = append(devices, opts.Zone)
+		}
+		if config.Spec.DNS.QueryLog.Enabled {
+			opts.QueryLog = ip.NewQueryLog(config.Spec.DNS.QueryLog.Size)
+			devices = append(devices, opts.QueryLog)
+		}
+		if len(devices) > 0 {
+			if err := dnshelpers.RunDeviceSync(ctx, devices...); err != nil {
+				util.Logger(ctx).Error("Error loading DNS devices", zap.Error(err))
+				return err
+			}
+		}
+
+		opts.Filter, err = dnshelpers.NewFilter()
+		if err != nil {
+			util.Logger(ctx).Error("Invalid DNS filter", zap.Error(err))
+			return err
+		}
+		if opts.Filter != nil {
+			if err := dnshelpers.RunFilterSync(ctx, opts.Filter); err != nil {
+				util.Logger(ctx).Error("Error loading DNS filter", zap.Error(err))
+				return err
+			}
+		}
+
+		dns, err = ip.New(ctx, opts)
 		if err != nil {
 			util.Logger(ctx).Error("Error creating DNS server", zap.Error(err))
 			return err
@@ -97,29 +190,41 @@ func RunServe(cmd *cobra.Command, args []string) error {
 	dh := device.NewDeviceHelpers(wg)
 	err = dh.RunSync(ctx)
 	if err != nil {
-		util.Logger(ctx).Fatal("Error running device sync", zap.Error(err))
+		util.Logger(ctx).Error("Error running device sync", zap.Error(err))
 		return err
 	}
 
-	auth.InitializeAuthBoss()
+	if config.Spec.Auth.LDAP.URL != "" {
+		go users.RunLDAPSync(ctx, dh)
+	}
+
+	if err = auth.InitializeAuthBoss(); err != nil {
+		util.Logger(ctx).Error("Error initializing auth", zap.Error(err))
+		return err
+	}
 
 	router := mux.NewRouter()
 	router.Use(middlewares.Logger)
-	router.Use(middlewares.CheckUser)
 	ab := auth.GetAuthBoss()
+	// the session has to be loaded before the user is looked up
 	router.Use(ab.LoadClientStateMiddleware, remember.Middleware(ab))
+	router.Use(middlewares.CheckUser)
 
 	router.Path("/ping").Methods(http.MethodGet).Handler(infra.CustomMux(controller.Ping))
 	a := router.PathPrefix("/").Subrouter()
 	a.Use(authboss.ModuleListMiddleware(ab))
-	a.PathPrefix("/auth").Handler(ab.LoadClientStateMiddleware(http.StripPrefix("/auth", ab.Config.Core.Router)))
+	a.PathPrefix("/auth").Handler(middlewares.Throttle(ab.LoadClientStateMiddleware(http.StripPrefix("/auth", ab.Config.Core.Router))))
 
 	site := router.PathPrefix("/").Subrouter()
 	// site.Use(authboss.Middleware2(ab, authboss.RequireNone, authboss.RespondUnauthorized))
-	site.PathPrefix("/api").Handler(controller.GRPCController(ctx, wg))
+	site.PathPrefix("/api").Handler(controller.GRPCController(ctx, wg, dns))
 
+	website, err := controller.WebsiteRouter(ctx)
+	if err != nil {
+		return err
+	}
 	w := router.PathPrefix("/").Subrouter()
-	w.PathPrefix("/").Handler(controller.WebsiteRouter(ctx))
+	w.PathPrefix("/").Handler(website)
 
 	address := fmt.Sprintf("0.0.0.0:%d", config.Spec.Port)
 	srv := &http.Server{
@@ -128,8 +233,25 @@ func RunServe(cmd *cobra.Command, args []string) error {
 	}
 
 	util.Logger(ctx).Info("Starting server on", zap.String("address", address))
-	if err := srv.ListenAndServe(); err != nil {
-		util.Logger(ctx).Fatal("Error starting server", zap.Error(err))
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		util.Logger(ctx).Info("Shutting down the WaaS server")
+	case err = <-serveErr:
+		util.Logger(ctx).Error("Error starting server", zap.Error(err))
+		return err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		util.Logger(ctx).Error("Error shutting down server", zap.Error(err))
 	}
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/waas-app/WaaS/cmd"
 	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/helpers/device"
 	"github.com/waas-app/WaaS/infra/red"
 	"github.com/waas-app/WaaS/model"
 	"github.com/waas-app/WaaS/util"
@@ -15,6 +16,7 @@ import (
 
 func main() {
 	ctx := context.Background()
+	cmd.Initialize(ctx)
 	if err := initRedisHandlerClient(ctx); err != nil {
 		return
 	}
@@ -58,7 +60,7 @@ func createDeviceHandler(ctx context.Context, msg *red.Message) error {
 		return err
 	}
 
-	if err = wg.AddPeer(payload.Device.PublicKey, payload.Device.Address); err != nil {
+	if err = device.ConfigurePeer(wg, payload.Device); err != nil {
 		util.Logger(ctx).Error("Error adding peer", zap.Error(err))
 		return err
 	}
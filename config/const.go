@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type CTXKey string
 
 func (k CTXKey) String() string {
@@ -17,6 +19,7 @@ const (
 	ServiceName     string   = "WaaS"
 	Worker          string   = "WaasWorker"
 	CurrentUser     string   = "currentUser"
+	CurrentAPIToken string   = "currentAPIToken"
 	CtxPubSubMethod CTXKey   = "pubSubHandler"
 	OutcomeSuccess           = "success"
 	OutcomeFailure           = "failure"
@@ -35,6 +38,10 @@ type Config struct {
 	Storage       string `mapstructure:"storage"`
 	Port          int    `mapstructure:"port"`
 	SessionSecret string `mapstructure:"session_secret"`
+	// EncryptionKey is used to encrypt secrets stored
+	// in the database such as device preshared keys.
+	// Defaults to the session secret if empty.
+	EncryptionKey string `mapstructure:"encryption_key"`
 	CookieDomain  string `mapstructure:"cookie_domain"`
 	Redis         string `mapstructure:"redis_url"`
 	WG            struct {
@@ -65,6 +72,11 @@ type Config struct {
 		// an IP address from
 		// defaults to 10.44.0.0/24
 		CIDR string `mapstructure:"cidr"`
+		// CIDRv6 optionally configures an IPv6 network
+		// address space (e.g. a ULA prefix like fd44::/64)
+		// so that every client also gets an IPv6 address.
+		// disabled if empty
+		CIDRv6 string `mapstructure:"cidrv6"`
 		// GatewayInterface will be used in iptable forwarding
 		// rules that send VPN traffic from clients to this interface
 		// Most use-cases will want this interface to have access
@@ -76,6 +88,22 @@ type Config struct {
 		// to enforce network access.
 		// defaults to ["0.0.0.0/0"]
 		AllowedIPs []string `mapstructure:"allowedIPs"`
+		// Policies restrict the network access of
+		// the devices owned by specific users or groups.
+		// Devices matched by at least one policy can only
+		// reach the destinations their policies allow,
+		// all other devices use AllowedIPs.
+		Policies []Policy `mapstructure:"policies"`
+		// Firewall selects how the forwarding and
+		// NAT rules are installed, either "iptables"
+		// or "nftables" for nftables-only hosts.
+		// defaults to "iptables"
+		Firewall string `mapstructure:"firewall"`
+		// ClientIsolation stops VPN clients from
+		// reaching each other unless a peer rule
+		// allows it.
+		// defaults to false
+		ClientIsolation bool `mapstructure:"clientIsolation"`
 	} `mapstructure:"vpn"`
 	// Configure the embeded DNS server
 	DNS struct {
@@ -87,9 +115,250 @@ type Config struct {
 		// DNS servers to which client DNS requests will be sent to.
 		// Defaults the host's upstream DNS servers (via resolveconf)
 		// or 1.1.1.1 if resolveconf cannot be used.
-		// NOTE: currently wg-access-server will only use the first upstream.
+		// Upstreams are tried in order, an upstream that
+		// keeps failing is skipped until it recovers.
+		// Plain addresses use UDP, tls://host:853 uses
+		// DNS-over-TLS and https://host/dns-query uses
+		// DNS-over-HTTPS.
 		Upstream []string `mapstructure:"upstream"`
+		// ForwardZones sends the queries for some domains
+		// and their subdomains to other upstreams, e.g.
+		// on-prem resolvers for corp.example.com or
+		// 10.in-addr.arpa. The longest matching zone is
+		// used, other queries go to Upstream.
+		ForwardZones []ForwardZone `mapstructure:"forwardZones"`
+		// Race is the number of upstreams queried in
+		// parallel, the fastest answer is used.
+		// defaults to 1, i.e. plain failover
+		Race int `mapstructure:"race"`
+		// Zone is answered by the DNS server with the
+		// addresses of the VPN devices, as
+		// <device>.<user>.<zone>, e.g. vpn.internal
+		// Reverse lookups of VPN addresses are answered
+		// too. Disabled when empty.
+		Zone string `mapstructure:"zone"`
+		// BindServerIP makes the DNS server listen on
+		// the WireGuard server IPs only instead of on
+		// every interface.
+		// defaults to false
+		BindServerIP bool `mapstructure:"bindServerIP"`
+		// Cache of upstream answers, entries expire
+		// with the TTL of their records
+		Cache struct {
+			// Size is the maximum number of cached answers
+			// defaults to 10000
+			Size int `mapstructure:"size"`
+			// Prefetch refreshes frequently used answers
+			// shortly before they expire.
+			// defaults to false
+			Prefetch bool `mapstructure:"prefetch"`
+		} `mapstructure:"cache"`
+		// QueryLog keeps the latest queries in memory
+		// with the device that sent them, searchable
+		// by admins.
+		QueryLog struct {
+			// Enabled can be turned off for privacy
+			// defaults to true
+			Enabled bool `mapstructure:"enabled"`
+			// Size is the number of queries kept
+			// defaults to 10000
+			Size int `mapstructure:"size"`
+		} `mapstructure:"queryLog"`
+		// Filter blocks domains for VPN clients
+		Filter struct {
+			// Policies are checked in order, the first
+			// one matching the device's owner is used.
+			// A policy without users and groups applies
+			// to everyone else.
+			// Filtering is disabled without policies.
+			Policies []FilterPolicy `mapstructure:"policies"`
+			// Response is the answer for blocked domains,
+			// either nxdomain or null (0.0.0.0 and ::)
+			// defaults to nxdomain
+			Response string `mapstructure:"response"`
+			// Refresh is how often the lists are reloaded
+			// defaults to 24h
+			Refresh time.Duration `mapstructure:"refresh"`
+		} `mapstructure:"filter"`
 	} `mapstructure:"dns"`
+	// Authentication options
+	Auth struct {
+		// DisableRegistration turns off signing up with
+		// an email and password, e.g. when users log in
+		// with OIDC only. Always off with LDAP.
+		// defaults to false
+		DisableRegistration bool `mapstructure:"disableRegistration"`
+		// GroupRoles gives members of a group a role,
+		// e.g. helpdesk: device-manager. The roles of
+		// OIDC and LDAP users are replaced with the
+		// roles of their groups when it's set.
+		GroupRoles map[string]string `mapstructure:"groupRoles"`
+		// OIDC logs users in with an OpenID Connect
+		// provider, users are created on their first
+		// login. Disabled when Issuer is empty.
+		OIDC struct {
+			// Issuer is the provider's URL, its
+			// configuration is discovered from
+			// <issuer>/.well-known/openid-configuration
+			Issuer       string `mapstructure:"issuer"`
+			ClientID     string `mapstructure:"clientID"`
+			ClientSecret string `mapstructure:"clientSecret"`
+			// RedirectURL must be registered with the
+			// provider.
+			// defaults to <root url>/auth/oidc/callback
+			RedirectURL string `mapstructure:"redirectURL"`
+			// Scopes requested besides openid
+			// defaults to profile and email
+			Scopes []string `mapstructure:"scopes"`
+			// GroupsClaim is the claim with the user's
+			// groups, used for network policies.
+			// defaults to groups
+			GroupsClaim string `mapstructure:"groupsClaim"`
+			// AdminGroup makes members of the group
+			// admins, and everyone else not.
+			// Admins aren't managed when empty.
+			AdminGroup string `mapstructure:"adminGroup"`
+		} `mapstructure:"oidc"`
+		// LDAP checks passwords against an LDAP server
+		// or Active Directory instead of the stored
+		// password. Users are created on their first
+		// login and disabled once they leave the
+		// directory. Disabled when URL is empty.
+		LDAP struct {
+			// URL of the server, e.g. ldaps://ldap.example.com
+			URL string `mapstructure:"url"`
+			// BindDN and BindPassword of the service
+			// account used to search for users
+			BindDN       string `mapstructure:"bindDN"`
+			BindPassword string `mapstructure:"bindPassword"`
+			// BaseDN users are searched in
+			BaseDN string `mapstructure:"baseDN"`
+			// UserFilter finds a user by the login, %s
+			// is replaced with the escaped login
+			// defaults to (&(objectClass=person)(mail=%s))
+			UserFilter string `mapstructure:"userFilter"`
+			// defaults to mail
+			EmailAttribute string `mapstructure:"emailAttribute"`
+			// defaults to cn
+			NameAttribute string `mapstructure:"nameAttribute"`
+			// GroupAttribute lists the user's group DNs,
+			// their common names become the user's groups
+			// defaults to memberOf
+			GroupAttribute string `mapstructure:"groupAttribute"`
+			// AdminGroup makes members of the group
+			// admins, and everyone else not.
+			// Admins aren't managed when empty.
+			AdminGroup string `mapstructure:"adminGroup"`
+			// StartTLS upgrades ldap:// connections
+			StartTLS           bool `mapstructure:"startTLS"`
+			InsecureSkipVerify bool `mapstructure:"insecureSkipVerify"`
+			// SyncInterval is how often users are checked
+			// against the directory
+			// defaults to 1h
+			SyncInterval time.Duration `mapstructure:"syncInterval"`
+		} `mapstructure:"ldap"`
+		// TOTP lets users add time-based one time
+		// passwords as a second factor, enrolled by
+		// scanning a QR code
+		TOTP struct {
+			// Issuer shown in authenticator apps
+			// defaults to WaaS
+			Issuer string `mapstructure:"issuer"`
+			// RequireForAdmins only grants admin access
+			// to sessions that logged in with a second
+			// factor. Admins without one can still log
+			// in to enroll.
+			// defaults to false
+			RequireForAdmins bool `mapstructure:"requireForAdmins"`
+		} `mapstructure:"totp"`
+		// Lock locks accounts after too many failed
+		// logins, they're unlocked after Duration
+		Lock struct {
+			// Attempts is the number of failed logins
+			// within Window that lock the account
+			// defaults to 5, 0 turns locking off
+			Attempts int `mapstructure:"attempts"`
+			// defaults to 15m
+			Window time.Duration `mapstructure:"window"`
+			// defaults to 30m
+			Duration time.Duration `mapstructure:"duration"`
+		} `mapstructure:"lock"`
+		// RateLimit throttles the requests to /auth
+		// per client IP with a token bucket stored in
+		// redis, so that it holds across replicas
+		RateLimit struct {
+			// PerMinute is how many requests a client
+			// may make per minute in the long run
+			// defaults to 20, 0 turns throttling off
+			PerMinute int `mapstructure:"perMinute"`
+			// Burst is how many requests a client
+			// may make at once
+			// defaults to 10
+			Burst int `mapstructure:"burst"`
+			// TrustedProxies are the CIDRs of reverse
+			// proxies whose X-Forwarded-For header is
+			// trusted to find the client IP
+			TrustedProxies []string `mapstructure:"trustedProxies"`
+		} `mapstructure:"rateLimit"`
+	} `mapstructure:"auth"`
+	// Mail sends password reset links, email
+	// confirmations when users sign up and
+	// invites from admins
+	Mail struct {
+		// SMTP server address, e.g. smtp.example.com:587
+		// Mail is disabled when it's empty.
+		SMTP string `mapstructure:"smtp"`
+		// Username and Password for SMTP auth, which
+		// needs TLS unless the server is on localhost
+		Username string `mapstructure:"username"`
+		Password string `mapstructure:"password"`
+		From     string `mapstructure:"from"`
+		// defaults to WaaS
+		FromName string `mapstructure:"fromName"`
+		// Templates is a directory with templates that
+		// replace the built in ones, e.g. invite.html
+		Templates string `mapstructure:"templates"`
+	} `mapstructure:"mail"`
 }
 
 var Spec Config
+
+// Policy is a network access policy for VPN clients.
+type Policy struct {
+	Name string `mapstructure:"name"`
+	// Users the policy applies to, by email
+	Users []string `mapstructure:"users"`
+	// Groups the policy applies to
+	Groups []string `mapstructure:"groups"`
+	// Allow lists the destinations that can be reached
+	// as a CIDR with an optional port and protocol
+	// e.g. 10.1.2.0/24:443 or 10.1.2.0/24:53/udp.
+	// IPv6 destinations with a port use brackets
+	// e.g. [fd00:1::/64]:443
+	// The protocol defaults to tcp when a port is set.
+	Allow []string `mapstructure:"allow"`
+}
+
+// ForwardZone is a domain resolved by its own upstreams.
+type ForwardZone struct {
+	Zone string `mapstructure:"zone"`
+	// Upstream accepts the same addresses as DNS.Upstream
+	Upstream []string `mapstructure:"upstream"`
+}
+
+// FilterPolicy selects the DNS blocklists for users and groups.
+type FilterPolicy struct {
+	// Name identifies the policy, it must be unique
+	Name string `mapstructure:"name"`
+	// Users the policy applies to, by email
+	Users []string `mapstructure:"users"`
+	// Groups the policy applies to
+	Groups []string `mapstructure:"groups"`
+	// Block lists files or http(s) URLs of domain
+	// lists in hosts-file format or with one domain
+	// per line. Subdomains are blocked too.
+	Block []string `mapstructure:"block"`
+	// Allow lists files or URLs in the same format
+	// with domains that are never blocked.
+	Allow []string `mapstructure:"allow"`
+}
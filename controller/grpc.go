@@ -12,13 +12,18 @@ import (
 	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"github.com/place1/wg-embed/pkg/wgembed"
 	"github.com/waas-app/WaaS/helpers/device"
+	dnshelpers "github.com/waas-app/WaaS/helpers/dns"
+	"github.com/waas-app/WaaS/helpers/tokens"
+	"github.com/waas-app/WaaS/helpers/users"
 	"github.com/waas-app/WaaS/helpers/vpn"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/ip"
 	"github.com/waas-app/WaaS/proto/proto"
 	"github.com/waas-app/WaaS/util"
 	"google.golang.org/grpc"
 )
 
-func GRPCController(ctx context.Context, wg wgembed.WireGuardInterface) http.Handler {
+func GRPCController(ctx context.Context, wg wgembed.WireGuardInterface, dns *ip.DNSServer) http.Handler {
 	var customFunc grpc_zap.CodeToLevel
 	opts := []grpc_zap.Option{
 		grpc_zap.WithLevels(customFunc),
@@ -28,6 +33,7 @@ func GRPCController(ctx context.Context, wg wgembed.WireGuardInterface) http.Han
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 			grpc_ctxtags.UnaryServerInterceptor(),
 			grpc_zap.UnaryServerInterceptor(util.Logger(ctx).ZapLogger(), opts...),
+			rbac.UnaryServerInterceptor(),
 		)),
 	}...)
 
@@ -39,6 +45,16 @@ func GRPCController(ctx context.Context, wg wgembed.WireGuardInterface) http.Han
 		DeviceHelpers: device.NewDeviceHelpers(wg),
 	})
 
+	proto.RegisterDNSServer(server, &dnshelpers.DNSSvc{
+		Server: dns,
+	})
+
+	proto.RegisterTokensServer(server, &tokens.TokenSvc{})
+
+	proto.RegisterUsersServer(server, &users.UserSvc{
+		DeviceHelpers: device.NewDeviceHelpers(wg),
+	})
+
 	// Grpc Web in process proxy (wrapper)
 	grpcServer := grpcweb.WrapServer(server,
 		grpcweb.WithAllowNonRootResource(true),
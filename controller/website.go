@@ -16,12 +16,13 @@ import (
 	"go.uber.org/zap"
 )
 
-func WebsiteRouter(ctx context.Context) *mux.Router {
+func WebsiteRouter(ctx context.Context) (*mux.Router, error) {
 	router := mux.NewRouter()
 
 	staticFiles, err := filepath.Abs("website/build")
 	if err != nil {
-		util.Logger(ctx).Fatal("failed to get absolute path to static files", zap.Error(err))
+		util.Logger(ctx).Error("failed to get absolute path to static files", zap.Error(err))
+		return nil, err
 	}
 
 	if _, err := os.Stat(staticFiles); os.IsNotExist(err) {
@@ -47,7 +48,7 @@ func WebsiteRouter(ctx context.Context) *mux.Router {
 			),
 		)
 	}
-	return router
+	return router, nil
 }
 
 // credit: https://gist.github.com/lummie/91cd1c18b2e32fa9f316862221a6fd5c
@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/infra/database"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+type APITokenStore interface {
+	Save(ctx context.Context, token *model.APIToken) error
+	ListForUser(ctx context.Context, userID uint) ([]*model.APIToken, error)
+	Get(ctx context.Context, id uint) (*model.APIToken, error)
+	FindByHash(ctx context.Context, hash string) (*model.APIToken, error)
+	Delete(ctx context.Context, token *model.APIToken) error
+}
+
+type apiTokenStore struct{}
+
+func NewAPITokenStore() APITokenStore {
+	return &apiTokenStore{}
+}
+
+func (s *apiTokenStore) Save(ctx context.Context, token *model.APIToken) error {
+	db := database.Instance(ctx)
+	if err := db.Save(token).Error; err != nil {
+		util.Logger(ctx).Error("Failed to save api token", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *apiTokenStore) ListForUser(ctx context.Context, userID uint) ([]*model.APIToken, error) {
+	db := database.Instance(ctx)
+	tokens := make([]*model.APIToken, 0)
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		util.Logger(ctx).Error("Failed to list api tokens", zap.Error(err))
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *apiTokenStore) Get(ctx context.Context, id uint) (*model.APIToken, error) {
+	db := database.Instance(ctx)
+	token := new(model.APIToken)
+	if err := db.First(token, "id = ?", id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to read api token")
+	}
+	return token, nil
+}
+
+func (s *apiTokenStore) FindByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	db := database.Instance(ctx)
+	token := new(model.APIToken)
+	if err := db.First(token, "hash = ?", hash).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to read api token")
+	}
+	return token, nil
+}
+
+func (s *apiTokenStore) Delete(ctx context.Context, token *model.APIToken) error {
+	db := database.Instance(ctx)
+	if err := db.Delete(token).Error; err != nil {
+		util.Logger(ctx).Error("Failed to delete api token", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 type DeviceStore interface {
 	Save(ctx context.Context, device *model.Device) error
+	Create(ctx context.Context, device *model.Device) error
 	List(ctx context.Context, owner string) ([]*model.Device, error)
 	Get(ctx context.Context, owner string, name string) (*model.Device, error)
 	GetByPublicKey(ctx context.Context, publicKey string) (*model.Device, error)
@@ -33,6 +34,17 @@ func (s *deviceStore) Save(ctx context.Context, device *model.Device) error {
 	return nil
 }
 
+// Create inserts a new device, it fails when
+// the owner already has a device with the name.
+func (s *deviceStore) Create(ctx context.Context, device *model.Device) error {
+	db := database.Instance(ctx)
+	if err := db.Create(device).Error; err != nil {
+		util.Logger(ctx).Error("Failed to create device", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func (s *deviceStore) List(ctx context.Context, owner string) ([]*model.Device, error) {
 	db := database.Instance(ctx)
 	devices := make([]*model.Device, 0)
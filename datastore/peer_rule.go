@@ -0,0 +1,61 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/infra/database"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+type PeerRuleStore interface {
+	Save(ctx context.Context, rule *model.PeerRule) error
+	List(ctx context.Context) ([]*model.PeerRule, error)
+	Get(ctx context.Context, id uint) (*model.PeerRule, error)
+	Delete(ctx context.Context, rule *model.PeerRule) error
+}
+
+type peerRuleStore struct{}
+
+func NewPeerRuleStore() PeerRuleStore {
+	return &peerRuleStore{}
+}
+
+func (s *peerRuleStore) Save(ctx context.Context, rule *model.PeerRule) error {
+	db := database.Instance(ctx)
+	if err := db.Save(rule).Error; err != nil {
+		util.Logger(ctx).Error("Failed to save peer rule", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *peerRuleStore) List(ctx context.Context) ([]*model.PeerRule, error) {
+	db := database.Instance(ctx)
+	rules := make([]*model.PeerRule, 0)
+	if err := db.Order("id").Find(&rules).Error; err != nil {
+		util.Logger(ctx).Error("Failed to list peer rules", zap.Error(err))
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *peerRuleStore) Get(ctx context.Context, id uint) (*model.PeerRule, error) {
+	db := database.Instance(ctx)
+	rule := new(model.PeerRule)
+	if err := db.First(rule, "id = ?", id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to read peer rule")
+	}
+	return rule, nil
+}
+
+func (s *peerRuleStore) Delete(ctx context.Context, rule *model.PeerRule) error {
+	db := database.Instance(ctx)
+	if err := db.Delete(rule).Error; err != nil {
+		util.Logger(ctx).Error("Failed to delete peer rule", zap.Error(err))
+		return err
+	}
+	return nil
+}
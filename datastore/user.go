@@ -14,6 +14,8 @@ type UserStore interface {
 	FindByQuery(ctx context.Context, query string, values ...interface{}) ([]*model.User, error)
 	FindUserByID(ctx context.Context, id uint) (*model.User, error)
 	FindUserByEmail(ctx context.Context, email string) (*model.User, error)
+	FindUserBySlug(ctx context.Context, slug string) (*model.User, error)
+	DeleteUser(ctx context.Context, user *model.User) error
 }
 
 func NewUserStore() UserStore {
@@ -63,3 +65,23 @@ func (userStore) SaveUser(ctx context.Context, user *model.User) error {
 	}
 	return nil
 }
+
+func (userStore) FindUserBySlug(ctx context.Context, slug string) (*model.User, error) {
+	db := database.Instance(ctx)
+	user := new(model.User)
+	if err := db.First(user, "slug = ?", slug).Error; err != nil {
+		util.Logger(ctx).Error("could not find user by slug", zap.Error(err), zap.String("slug", slug))
+		return nil, err
+	}
+	return user, nil
+}
+
+func (userStore) DeleteUser(ctx context.Context, user *model.User) error {
+	db := database.Instance(ctx)
+
+	if err := db.Delete(user).Error; err != nil {
+		util.Logger(ctx).Error("could not delete user", zap.Error(err), zap.String("email", user.Email))
+		return err
+	}
+	return nil
+}
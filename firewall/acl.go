@@ -0,0 +1,142 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+)
+
+// Destination is a network a device may reach, optionally
+// limited to a single port and protocol.
+type Destination struct {
+	CIDR     string
+	Protocol string
+	Port     string
+}
+
+// ACL lists the only destinations a device may reach.
+type ACL struct {
+	Device       string
+	Sources      []string
+	Destinations []*Destination
+}
+
+// ParseDestination parses a policy destination such as 10.1.2.0/24,
+// 10.1.2.0/24:443, 10.1.2.0/24:53/udp or [fd00:1::/64]:443.
+func ParseDestination(value string) (*Destination, error) {
+	dest := &Destination{}
+	rest := strings.TrimSpace(value)
+
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		if proto := strings.ToLower(rest[i+1:]); proto == "tcp" || proto == "udp" {
+			dest.Protocol = proto
+			rest = rest[:i]
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "["):
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("invalid destination '%s'", value)
+		}
+		dest.CIDR = rest[1:end]
+		if port := rest[end+1:]; port != "" {
+			if !strings.HasPrefix(port, ":") {
+				return nil, fmt.Errorf("invalid destination '%s'", value)
+			}
+			dest.Port = port[1:]
+		}
+	case strings.Count(rest, ":") == 1:
+		parts := strings.SplitN(rest, ":", 2)
+		dest.CIDR, dest.Port = parts[0], parts[1]
+	default:
+		dest.CIDR = rest
+	}
+
+	if !strings.Contains(dest.CIDR, "/") {
+		if ip.IsIPv6(dest.CIDR) {
+			dest.CIDR += "/128"
+		} else {
+			dest.CIDR += "/32"
+		}
+	}
+	_, network, err := net.ParseCIDR(dest.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination '%s': %w", value, err)
+	}
+	dest.CIDR = network.String()
+
+	if dest.Port != "" {
+		if port, err := strconv.Atoi(dest.Port); err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in destination '%s'", value)
+		}
+		if dest.Protocol == "" {
+			dest.Protocol = "tcp"
+		}
+	}
+
+	return dest, nil
+}
+
+// Applies reports whether a policy applies to a user.
+func Applies(policy config.Policy, user *model.User) bool {
+	if user == nil {
+		return false
+	}
+	for _, email := range policy.Users {
+		if strings.EqualFold(email, user.Email) {
+			return true
+		}
+	}
+	for _, group := range policy.Groups {
+		if user.InGroup(group) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileACLs turns policies into per-device ACLs. users maps a user's
+// slug (the device owner) to the user. Devices whose owner isn't matched
+// by any policy get no ACL.
+func CompileACLs(policies []config.Policy, devices []*model.Device, users map[string]*model.User) ([]*ACL, error) {
+	// policies are told apart by their position, names
+	// are optional and don't have to be unique
+	parsed := make([][]*Destination, len(policies))
+	for i, policy := range policies {
+		for _, allow := range policy.Allow {
+			dest, err := ParseDestination(allow)
+			if err != nil {
+				return nil, fmt.Errorf("policy '%s': %w", policy.Name, err)
+			}
+			parsed[i] = append(parsed[i], dest)
+		}
+	}
+
+	acls := []*ACL{}
+	for _, device := range devices {
+		var acl *ACL
+		for i, policy := range policies {
+			if !Applies(policy, users[device.Owner]) {
+				continue
+			}
+			if acl == nil {
+				acl = &ACL{
+					Device:  fmt.Sprintf("%s/%s", device.Owner, device.Name),
+					Sources: device.Address,
+				}
+			}
+			acl.Destinations = append(acl.Destinations, parsed[i]...)
+		}
+		if acl != nil {
+			acls = append(acls, acl)
+		}
+	}
+	return acls, nil
+}
@@ -0,0 +1,204 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/waas-app/WaaS/ip"
+)
+
+const (
+	IPTables = "iptables"
+	NFTables = "nftables"
+)
+
+type Family string
+
+const (
+	IPv4 Family = "ipv4"
+	IPv6 Family = "ipv6"
+)
+
+type Verdict string
+
+const (
+	Accept     Verdict = "accept"
+	Reject     Verdict = "reject"
+	Masquerade Verdict = "masquerade"
+)
+
+// Rule is a single backend independent firewall rule. Empty
+// fields match anything.
+type Rule struct {
+	Family       Family
+	Source       string
+	Destination  string
+	Protocol     string
+	Port         string
+	OutInterface string
+	Verdict      Verdict
+}
+
+func (r *Rule) String() string {
+	parts := []string{string(r.Family)}
+	if r.Source != "" {
+		parts = append(parts, "saddr "+r.Source)
+	}
+	if r.Destination != "" {
+		parts = append(parts, "daddr "+r.Destination)
+	}
+	if r.Port != "" {
+		parts = append(parts, fmt.Sprintf("%s dport %s", r.Protocol, r.Port))
+	}
+	if r.OutInterface != "" {
+		parts = append(parts, "oifname "+r.OutInterface)
+	}
+	return strings.Join(append(parts, string(r.Verdict)), " ")
+}
+
+// Ruleset is the complete set of rules the server needs. Forward
+// rules are evaluated in order for forwarded client traffic and
+// Postrouting rules set up NAT towards the gateway.
+type Ruleset struct {
+	Families    []Family
+	Forward     []*Rule
+	Postrouting []*Rule
+}
+
+// Options describe the network the rules are generated for.
+type Options struct {
+	CIDRs            []string
+	AllowedIPs       []string
+	GatewayInterface string
+	ACLs             []*ACL
+	// ClientIsolation stops clients from reaching each
+	// other except for the devices in PeerLinks.
+	ClientIsolation bool
+	PeerLinks       []*PeerLink
+}
+
+// PeerLink lets every address in A reach every address
+// in B and the other way around.
+type PeerLink struct {
+	A []string
+	B []string
+}
+
+// Backend applies a ruleset to the host. Applying replaces
+// whatever rules the backend installed before.
+type Backend interface {
+	Apply(ctx context.Context, rules *Ruleset) error
+	// Teardown removes everything the backend installed
+	// for the given families.
+	Teardown(ctx context.Context, families []Family) error
+	// Commands returns the shell commands that are
+	// equivalent to applying the ruleset.
+	Commands(rules *Ruleset) []string
+}
+
+// New returns the backend with the given name.
+func New(name string) (Backend, error) {
+	switch name {
+	case "", IPTables:
+		return NewIPTables(), nil
+	case NFTables:
+		return NewNFTables(), nil
+	default:
+		return nil, fmt.Errorf("unknown firewall backend '%s'", name)
+	}
+}
+
+// Configure generates the rules for the network and applies them.
+func Configure(ctx context.Context, backend Backend, opts Options) error {
+	return backend.Apply(ctx, Plan(opts))
+}
+
+// Plan generates the rules for the network without touching the host.
+func Plan(opts Options) *Ruleset {
+	rules := &Ruleset{}
+
+	for _, cidr := range opts.CIDRs {
+		family := familyOf(cidr)
+		rules.Families = append(rules.Families, family)
+
+		if opts.ClientIsolation {
+			rules.Forward = append(rules.Forward, peerRules(family, opts.PeerLinks)...)
+			rules.Forward = append(rules.Forward, &Rule{Family: family, Source: cidr, Destination: cidr, Verdict: Reject})
+		}
+
+		// Devices with an ACL only get the destinations it allows,
+		// these rules must come before the subnet wide ones.
+		for _, acl := range opts.ACLs {
+			for _, source := range acl.Sources {
+				if familyOf(source) != family {
+					continue
+				}
+				for _, dest := range acl.Destinations {
+					if familyOf(dest.CIDR) != family {
+						continue
+					}
+					rules.Forward = append(rules.Forward, &Rule{
+						Family:      family,
+						Source:      source,
+						Destination: dest.CIDR,
+						Protocol:    dest.Protocol,
+						Port:        dest.Port,
+						Verdict:     Accept,
+					})
+				}
+				rules.Forward = append(rules.Forward, &Rule{Family: family, Source: source, Verdict: Reject})
+			}
+		}
+
+		// Accept client traffic for given allowed ips
+		for _, allowedCIDR := range opts.AllowedIPs {
+			if familyOf(allowedCIDR) != family {
+				continue
+			}
+			rules.Forward = append(rules.Forward, &Rule{Family: family, Source: cidr, Destination: allowedCIDR, Verdict: Accept})
+		}
+
+		if opts.GatewayInterface != "" {
+			rules.Postrouting = append(rules.Postrouting, &Rule{Family: family, Source: cidr, OutInterface: opts.GatewayInterface, Verdict: Masquerade})
+		}
+
+		rules.Forward = append(rules.Forward, &Rule{Family: family, Source: cidr, Verdict: Reject})
+	}
+
+	return rules
+}
+
+// peerRules accepts traffic between linked devices in both directions.
+func peerRules(family Family, links []*PeerLink) []*Rule {
+	rules := []*Rule{}
+	seen := map[string]bool{}
+	accept := func(source string, destination string) {
+		key := source + ">" + destination
+		if source == destination || seen[key] {
+			return
+		}
+		seen[key] = true
+		rules = append(rules, &Rule{Family: family, Source: source, Destination: destination, Verdict: Accept})
+	}
+
+	for _, link := range links {
+		for _, a := range link.A {
+			for _, b := range link.B {
+				if familyOf(a) != family || familyOf(b) != family {
+					continue
+				}
+				accept(a, b)
+				accept(b, a)
+			}
+		}
+	}
+	return rules
+}
+
+func familyOf(cidr string) Family {
+	if ip.IsIPv6(cidr) {
+		return IPv6
+	}
+	return IPv4
+}
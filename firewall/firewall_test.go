@@ -0,0 +1,294 @@
+package firewall
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+func forwardRules(rules *Ruleset) []string {
+	lines := []string{}
+	for _, rule := range rules.Forward {
+		lines = append(lines, rule.String())
+	}
+	return lines
+}
+
+func assertLines(t *testing.T, got []string, want []string) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected rules\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPlanDefault(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:            []string{"10.44.0.0/24"},
+		AllowedIPs:       []string{"0.0.0.0/0"},
+		GatewayInterface: "eth0",
+	})
+
+	assertLines(t, forwardRules(rules), []string{
+		"ipv4 saddr 10.44.0.0/24 daddr 0.0.0.0/0 accept",
+		"ipv4 saddr 10.44.0.0/24 reject",
+	})
+	if len(rules.Postrouting) != 1 || rules.Postrouting[0].String() != "ipv4 saddr 10.44.0.0/24 oifname eth0 masquerade" {
+		t.Fatalf("unexpected postrouting rules %v", rules.Postrouting)
+	}
+}
+
+func TestPlanACLsComeFirst(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:      []string{"10.44.0.0/24"},
+		AllowedIPs: []string{"0.0.0.0/0"},
+		ACLs: []*ACL{{
+			Device:  "alice/laptop",
+			Sources: []string{"10.44.0.2/32"},
+			Destinations: []*Destination{
+				{CIDR: "10.1.0.0/16"},
+				{CIDR: "10.2.0.5/32", Protocol: "udp", Port: "53"},
+			},
+		}},
+	})
+
+	// the device's reject has to come before the subnet wide accept
+	assertLines(t, forwardRules(rules), []string{
+		"ipv4 saddr 10.44.0.2/32 daddr 10.1.0.0/16 accept",
+		"ipv4 saddr 10.44.0.2/32 daddr 10.2.0.5/32 udp dport 53 accept",
+		"ipv4 saddr 10.44.0.2/32 reject",
+		"ipv4 saddr 10.44.0.0/24 daddr 0.0.0.0/0 accept",
+		"ipv4 saddr 10.44.0.0/24 reject",
+	})
+}
+
+func TestPlanClientIsolation(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:           []string{"10.44.0.0/24"},
+		AllowedIPs:      []string{"0.0.0.0/0"},
+		ClientIsolation: true,
+		PeerLinks: []*PeerLink{
+			{A: []string{"10.44.0.2/32"}, B: []string{"10.44.0.3/32"}},
+			// links in both directions are only added once
+			{A: []string{"10.44.0.3/32"}, B: []string{"10.44.0.2/32"}},
+		},
+	})
+
+	assertLines(t, forwardRules(rules), []string{
+		"ipv4 saddr 10.44.0.2/32 daddr 10.44.0.3/32 accept",
+		"ipv4 saddr 10.44.0.3/32 daddr 10.44.0.2/32 accept",
+		"ipv4 saddr 10.44.0.0/24 daddr 10.44.0.0/24 reject",
+		"ipv4 saddr 10.44.0.0/24 daddr 0.0.0.0/0 accept",
+		"ipv4 saddr 10.44.0.0/24 reject",
+	})
+}
+
+func TestPlanFamilies(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:            []string{"10.44.0.0/24", "fd44::/64"},
+		AllowedIPs:       []string{"0.0.0.0/0", "::/0"},
+		GatewayInterface: "eth0",
+		ClientIsolation:  true,
+		PeerLinks: []*PeerLink{
+			{A: []string{"10.44.0.2/32", "fd44::2/128"}, B: []string{"10.44.0.3/32", "fd44::3/128"}},
+		},
+		ACLs: []*ACL{{
+			Sources:      []string{"10.44.0.2/32", "fd44::2/128"},
+			Destinations: []*Destination{{CIDR: "10.1.0.0/16"}, {CIDR: "fd00:1::/64"}},
+		}},
+	})
+
+	if !reflect.DeepEqual(rules.Families, []Family{IPv4, IPv6}) {
+		t.Fatalf("unexpected families %v", rules.Families)
+	}
+
+	// addresses are never mixed across families
+	assertLines(t, forwardRules(rules), []string{
+		"ipv4 saddr 10.44.0.2/32 daddr 10.44.0.3/32 accept",
+		"ipv4 saddr 10.44.0.3/32 daddr 10.44.0.2/32 accept",
+		"ipv4 saddr 10.44.0.0/24 daddr 10.44.0.0/24 reject",
+		"ipv4 saddr 10.44.0.2/32 daddr 10.1.0.0/16 accept",
+		"ipv4 saddr 10.44.0.2/32 reject",
+		"ipv4 saddr 10.44.0.0/24 daddr 0.0.0.0/0 accept",
+		"ipv4 saddr 10.44.0.0/24 reject",
+		"ipv6 saddr fd44::2/128 daddr fd44::3/128 accept",
+		"ipv6 saddr fd44::3/128 daddr fd44::2/128 accept",
+		"ipv6 saddr fd44::/64 daddr fd44::/64 reject",
+		"ipv6 saddr fd44::2/128 daddr fd00:1::/64 accept",
+		"ipv6 saddr fd44::2/128 reject",
+		"ipv6 saddr fd44::/64 daddr ::/0 accept",
+		"ipv6 saddr fd44::/64 reject",
+	})
+	if len(rules.Postrouting) != 2 || rules.Postrouting[1].Family != IPv6 {
+		t.Fatalf("unexpected postrouting rules %v", rules.Postrouting)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	recorder := NewRecorder()
+	opts := Options{CIDRs: []string{"10.44.0.0/24"}, AllowedIPs: []string{"0.0.0.0/0"}}
+	if err := Configure(context.Background(), recorder, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	applied := recorder.Applied()
+	if len(applied) != 1 || !reflect.DeepEqual(applied[0], Plan(opts)) {
+		t.Fatalf("expected the plan to be applied, got %v", applied)
+	}
+}
+
+func TestIPTablesCommands(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:            []string{"10.44.0.0/24", "fd44::/64"},
+		AllowedIPs:       []string{"0.0.0.0/0", "::/0"},
+		GatewayInterface: "eth0",
+		ACLs: []*ACL{{
+			Sources:      []string{"10.44.0.2/32"},
+			Destinations: []*Destination{{CIDR: "10.2.0.5/32", Protocol: "tcp", Port: "443"}},
+		}},
+	})
+
+	assertLines(t, NewIPTables().Commands(rules), []string{
+		"iptables-restore --noflush <<'EOF'",
+		"*filter",
+		":WG_ACCESS_SERVER_FORWARD - [0:0]",
+		"-A WG_ACCESS_SERVER_FORWARD -s 10.44.0.2/32 -d 10.2.0.5/32 -p tcp --dport 443 -j ACCEPT",
+		"-A WG_ACCESS_SERVER_FORWARD -s 10.44.0.2/32 -j REJECT",
+		"-A WG_ACCESS_SERVER_FORWARD -s 10.44.0.0/24 -d 0.0.0.0/0 -j ACCEPT",
+		"-A WG_ACCESS_SERVER_FORWARD -s 10.44.0.0/24 -j REJECT",
+		"COMMIT",
+		"*nat",
+		":WG_ACCESS_SERVER_POSTROUTING - [0:0]",
+		"-A WG_ACCESS_SERVER_POSTROUTING -s 10.44.0.0/24 -o eth0 -j MASQUERADE",
+		"COMMIT",
+		"EOF",
+		"iptables -t filter -C FORWARD -j WG_ACCESS_SERVER_FORWARD || iptables -t filter -A FORWARD -j WG_ACCESS_SERVER_FORWARD",
+		"iptables -t nat -C POSTROUTING -j WG_ACCESS_SERVER_POSTROUTING || iptables -t nat -A POSTROUTING -j WG_ACCESS_SERVER_POSTROUTING",
+		"ip6tables-restore --noflush <<'EOF'",
+		"*filter",
+		":WG_ACCESS_SERVER_FORWARD - [0:0]",
+		"-A WG_ACCESS_SERVER_FORWARD -s fd44::/64 -d ::/0 -j ACCEPT",
+		"-A WG_ACCESS_SERVER_FORWARD -s fd44::/64 -j REJECT",
+		"COMMIT",
+		"*nat",
+		":WG_ACCESS_SERVER_POSTROUTING - [0:0]",
+		"-A WG_ACCESS_SERVER_POSTROUTING -s fd44::/64 -o eth0 -j MASQUERADE",
+		"COMMIT",
+		"EOF",
+		"ip6tables -t filter -C FORWARD -j WG_ACCESS_SERVER_FORWARD || ip6tables -t filter -A FORWARD -j WG_ACCESS_SERVER_FORWARD",
+		"ip6tables -t nat -C POSTROUTING -j WG_ACCESS_SERVER_POSTROUTING || ip6tables -t nat -A POSTROUTING -j WG_ACCESS_SERVER_POSTROUTING",
+	})
+}
+
+func TestNFTablesCommands(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:            []string{"10.44.0.0/24", "fd44::/64"},
+		AllowedIPs:       []string{"0.0.0.0/0", "::/0"},
+		GatewayInterface: "eth0",
+		ACLs: []*ACL{{
+			Sources:      []string{"fd44::2/128"},
+			Destinations: []*Destination{{CIDR: "fd00:1::/64", Protocol: "udp", Port: "53"}},
+		}},
+	})
+
+	assertLines(t, NewNFTables().Commands(rules), []string{
+		"nft add table inet waas",
+		"nft flush table inet waas",
+		"nft add chain inet waas forward '{ type filter hook forward priority 0; policy accept; }'",
+		"nft add chain inet waas postrouting '{ type nat hook postrouting priority 100; policy accept; }'",
+		"nft add rule inet waas forward meta nfproto ipv4 ip saddr 10.44.0.0/24 ip daddr 0.0.0.0/0 accept",
+		"nft add rule inet waas forward meta nfproto ipv4 ip saddr 10.44.0.0/24 reject with icmpx type port-unreachable",
+		"nft add rule inet waas forward meta nfproto ipv6 ip6 saddr fd44::2/128 ip6 daddr fd00:1::/64 meta l4proto udp th dport 53 accept",
+		"nft add rule inet waas forward meta nfproto ipv6 ip6 saddr fd44::2/128 reject with icmpx type port-unreachable",
+		"nft add rule inet waas forward meta nfproto ipv6 ip6 saddr fd44::/64 ip6 daddr ::/0 accept",
+		"nft add rule inet waas forward meta nfproto ipv6 ip6 saddr fd44::/64 reject with icmpx type port-unreachable",
+		"nft add rule inet waas postrouting meta nfproto ipv4 ip saddr 10.44.0.0/24 oifname \"eth0\" masquerade",
+		"nft add rule inet waas postrouting meta nfproto ipv6 ip6 saddr fd44::/64 oifname \"eth0\" masquerade",
+	})
+}
+
+func TestNFTablesExprs(t *testing.T) {
+	rules := Plan(Options{
+		CIDRs:            []string{"10.44.0.0/24", "fd44::/64"},
+		AllowedIPs:       []string{"0.0.0.0/0", "::/0"},
+		GatewayInterface: "eth0",
+		ClientIsolation:  true,
+		ACLs: []*ACL{{
+			Sources:      []string{"10.44.0.2/32"},
+			Destinations: []*Destination{{CIDR: "10.2.0.5/32", Protocol: "tcp", Port: "443"}},
+		}},
+	})
+	for _, rule := range append(rules.Forward, rules.Postrouting...) {
+		if _, err := nftablesExprs(rule); err != nil {
+			t.Errorf("rule '%s': %s", rule, err)
+		}
+	}
+
+	if _, err := nftablesExprs(&Rule{Family: IPv4, Verdict: "drop"}); err == nil {
+		t.Error("expected unknown verdicts to fail")
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	for value, want := range map[string]Destination{
+		"10.1.2.0/24":        {CIDR: "10.1.2.0/24"},
+		"10.1.2.3":           {CIDR: "10.1.2.3/32"},
+		"10.1.2.0/24:443":    {CIDR: "10.1.2.0/24", Protocol: "tcp", Port: "443"},
+		"10.1.2.0/24:53/udp": {CIDR: "10.1.2.0/24", Protocol: "udp", Port: "53"},
+		"[fd00:1::/64]:443":  {CIDR: "fd00:1::/64", Protocol: "tcp", Port: "443"},
+		"fd00:1::1":          {CIDR: "fd00:1::1/128"},
+	} {
+		dest, err := ParseDestination(value)
+		if err != nil {
+			t.Errorf("%s: %s", value, err)
+			continue
+		}
+		if *dest != want {
+			t.Errorf("%s: got %+v, want %+v", value, *dest, want)
+		}
+	}
+
+	for _, value := range []string{"nope", "10.1.2.0/24:0", "10.1.2.0/24:http", "[fd00:1::/64"} {
+		if _, err := ParseDestination(value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestCompileACLsUnnamedPolicies(t *testing.T) {
+	policies := []config.Policy{
+		{Users: []string{"alice@example.com"}, Allow: []string{"10.1.0.0/16"}},
+		{Groups: []string{"admins"}, Allow: []string{"10.2.0.0/16"}},
+	}
+	devices := []*model.Device{
+		{Owner: "alice", Name: "laptop", Address: model.AddressList{"10.44.0.2/32"}},
+		{Owner: "bob", Name: "phone", Address: model.AddressList{"10.44.0.3/32"}},
+	}
+	users := map[string]*model.User{
+		"alice": {Email: "alice@example.com"},
+		"bob":   {Email: "bob@example.com", Groups: []string{"admins"}},
+	}
+
+	acls, err := CompileACLs(policies, devices, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each user only gets the destinations of their own policy
+	got := map[string][]string{}
+	for _, acl := range acls {
+		for _, dest := range acl.Destinations {
+			got[acl.Device] = append(got[acl.Device], dest.CIDR)
+		}
+	}
+	want := map[string][]string{
+		"alice/laptop": {"10.1.0.0/16"},
+		"bob/phone":    {"10.2.0.0/16"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got destinations %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/pkg/errors"
+)
+
+const (
+	iptablesForwardChain     = "WG_ACCESS_SERVER_FORWARD"
+	iptablesPostroutingChain = "WG_ACCESS_SERVER_POSTROUTING"
+)
+
+type iptablesBackend struct{}
+
+// NewIPTables returns a backend that manages our own chains with
+// iptables for IPv4 and ip6tables for IPv6 rules.
+func NewIPTables() Backend {
+	return &iptablesBackend{}
+}
+
+// Apply replaces the contents of our chains with iptables-restore, which
+// commits each table at once. Flushing the chains and appending the rules
+// one by one would let forwarded traffic through while they're rebuilt.
+func (b *iptablesBackend) Apply(ctx context.Context, rules *Ruleset) error {
+	for _, family := range rules.Families {
+		ipt, err := iptables.NewWithProtocol(iptablesProtocol(family))
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.CommandContext(ctx, iptablesCommand(family)+"-restore", "--noflush")
+		cmd.Stdin = strings.NewReader(strings.Join(iptablesRestoreInput(rules, family), "\n") + "\n")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "failed to set ip tables rules: %s", strings.TrimSpace(string(out)))
+		}
+
+		if err := ipt.AppendUnique("filter", "FORWARD", "-j", iptablesForwardChain); err != nil {
+			return errors.Wrap(err, "failed to set ip tables rule")
+		}
+		if err := ipt.AppendUnique("nat", "POSTROUTING", "-j", iptablesPostroutingChain); err != nil {
+			return errors.Wrap(err, "failed to set ip tables rule")
+		}
+	}
+
+	return nil
+}
+
+func (b *iptablesBackend) Teardown(ctx context.Context, families []Family) error {
+	for _, family := range families {
+		ipt, err := iptables.NewWithProtocol(iptablesProtocol(family))
+		if err != nil {
+			return err
+		}
+
+		if err := ipt.DeleteIfExists("filter", "FORWARD", "-j", iptablesForwardChain); err != nil {
+			return errors.Wrap(err, "failed to remove ip tables rule")
+		}
+		if err := ipt.ClearAndDeleteChain("filter", iptablesForwardChain); err != nil {
+			return errors.Wrap(err, "failed to remove ip tables chain")
+		}
+		if err := ipt.DeleteIfExists("nat", "POSTROUTING", "-j", iptablesPostroutingChain); err != nil {
+			return errors.Wrap(err, "failed to remove ip tables rule")
+		}
+		if err := ipt.ClearAndDeleteChain("nat", iptablesPostroutingChain); err != nil {
+			return errors.Wrap(err, "failed to remove ip tables chain")
+		}
+	}
+	return nil
+}
+
+func (b *iptablesBackend) Commands(rules *Ruleset) []string {
+	commands := []string{}
+	for _, family := range rules.Families {
+		command := iptablesCommand(family)
+		commands = append(commands, command+"-restore --noflush <<'EOF'")
+		commands = append(commands, iptablesRestoreInput(rules, family)...)
+		commands = append(commands, "EOF")
+		for _, jump := range [][]string{{"filter", "FORWARD", iptablesForwardChain}, {"nat", "POSTROUTING", iptablesPostroutingChain}} {
+			rule := fmt.Sprintf("-t %s %%s %s -j %s", jump[0], jump[1], jump[2])
+			commands = append(commands, fmt.Sprintf("%s %s || %s %s", command, fmt.Sprintf(rule, "-C"), command, fmt.Sprintf(rule, "-A")))
+		}
+	}
+	return commands
+}
+
+// iptablesRestoreInput returns the iptables-restore lines for the family.
+// With --noflush, declaring our chains creates or flushes them and leaves
+// the rest of the tables alone.
+func iptablesRestoreInput(rules *Ruleset, family Family) []string {
+	lines := []string{}
+	for _, table := range []struct {
+		name  string
+		chain string
+		rules []*Rule
+	}{{"filter", iptablesForwardChain, rules.Forward}, {"nat", iptablesPostroutingChain, rules.Postrouting}} {
+		lines = append(lines, "*"+table.name, fmt.Sprintf(":%s - [0:0]", table.chain))
+		for _, rule := range table.rules {
+			if rule.Family == family {
+				lines = append(lines, strings.Join(append([]string{"-A", table.chain}, iptablesRuleArgs(rule)...), " "))
+			}
+		}
+		lines = append(lines, "COMMIT")
+	}
+	return lines
+}
+
+func iptablesCommand(family Family) string {
+	if family == IPv6 {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func iptablesProtocol(family Family) iptables.Protocol {
+	if family == IPv6 {
+		return iptables.ProtocolIPv6
+	}
+	return iptables.ProtocolIPv4
+}
+
+func iptablesRuleArgs(rule *Rule) []string {
+	args := []string{}
+	if rule.Source != "" {
+		args = append(args, "-s", rule.Source)
+	}
+	if rule.Destination != "" {
+		args = append(args, "-d", rule.Destination)
+	}
+	if rule.Port != "" {
+		args = append(args, "-p", rule.Protocol, "--dport", rule.Port)
+	}
+	if rule.OutInterface != "" {
+		args = append(args, "-o", rule.OutInterface)
+	}
+
+	switch rule.Verdict {
+	case Accept:
+		args = append(args, "-j", "ACCEPT")
+	case Reject:
+		args = append(args, "-j", "REJECT")
+	case Masquerade:
+		args = append(args, "-j", "MASQUERADE")
+	}
+	return args
+}
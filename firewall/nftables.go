@@ -0,0 +1,242 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const nftablesTable = "waas"
+
+type nftablesBackend struct{}
+
+// NewNFTables returns a backend that keeps all rules in a dedicated
+// inet table so IPv4 and IPv6 share the same chains.
+func NewNFTables() Backend {
+	return &nftablesBackend{}
+}
+
+func (b *nftablesBackend) Apply(ctx context.Context, rules *Ruleset) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to open netlink connection")
+	}
+
+	table := conn.AddTable(&nftables.Table{
+		Family: nftables.TableFamilyINet,
+		Name:   nftablesTable,
+	})
+	// Remove the rules from a previous run, the table
+	// and its chains are created if they don't exist.
+	conn.FlushTable(table)
+
+	accept := nftables.ChainPolicyAccept
+	forward := conn.AddChain(&nftables.Chain{
+		Name:     "forward",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &accept,
+	})
+	postrouting := conn.AddChain(&nftables.Chain{
+		Name:     "postrouting",
+		Table:    table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+		Policy:   &accept,
+	})
+
+	for _, chain := range []struct {
+		chain *nftables.Chain
+		rules []*Rule
+	}{{forward, rules.Forward}, {postrouting, rules.Postrouting}} {
+		for _, rule := range chain.rules {
+			exprs, err := nftablesExprs(rule)
+			if err != nil {
+				return errors.Wrapf(err, "invalid rule '%s'", rule)
+			}
+			conn.AddRule(&nftables.Rule{
+				Table: table,
+				Chain: chain.chain,
+				Exprs: exprs,
+			})
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return errors.Wrap(err, "failed to set nftables rules")
+	}
+	return nil
+}
+
+func (b *nftablesBackend) Teardown(ctx context.Context, families []Family) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to open netlink connection")
+	}
+
+	tables, err := conn.ListTablesOfFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nftables tables")
+	}
+
+	for _, table := range tables {
+		if table.Name == nftablesTable {
+			conn.DelTable(table)
+			if err := conn.Flush(); err != nil {
+				return errors.Wrap(err, "failed to remove nftables table")
+			}
+		}
+	}
+	return nil
+}
+
+func (b *nftablesBackend) Commands(rules *Ruleset) []string {
+	commands := []string{
+		fmt.Sprintf("nft add table inet %s", nftablesTable),
+		fmt.Sprintf("nft flush table inet %s", nftablesTable),
+		fmt.Sprintf("nft add chain inet %s forward '{ type filter hook forward priority 0; policy accept; }'", nftablesTable),
+		fmt.Sprintf("nft add chain inet %s postrouting '{ type nat hook postrouting priority 100; policy accept; }'", nftablesTable),
+	}
+	for _, rule := range rules.Forward {
+		commands = append(commands, fmt.Sprintf("nft add rule inet %s forward %s", nftablesTable, nftablesStatement(rule)))
+	}
+	for _, rule := range rules.Postrouting {
+		commands = append(commands, fmt.Sprintf("nft add rule inet %s postrouting %s", nftablesTable, nftablesStatement(rule)))
+	}
+	return commands
+}
+
+// nftablesStatement is the nft syntax for the expressions
+// built by nftablesExprs.
+func nftablesStatement(rule *Rule) string {
+	proto := "ip"
+	if rule.Family == IPv6 {
+		proto = "ip6"
+	}
+
+	parts := []string{"meta nfproto " + string(rule.Family)}
+	if rule.Source != "" {
+		parts = append(parts, fmt.Sprintf("%s saddr %s", proto, rule.Source))
+	}
+	if rule.Destination != "" {
+		parts = append(parts, fmt.Sprintf("%s daddr %s", proto, rule.Destination))
+	}
+	if rule.Port != "" {
+		parts = append(parts, fmt.Sprintf("meta l4proto %s th dport %s", rule.Protocol, rule.Port))
+	}
+	if rule.OutInterface != "" {
+		parts = append(parts, fmt.Sprintf("oifname \"%s\"", rule.OutInterface))
+	}
+
+	switch rule.Verdict {
+	case Reject:
+		parts = append(parts, "reject with icmpx type port-unreachable")
+	default:
+		parts = append(parts, string(rule.Verdict))
+	}
+	return strings.Join(parts, " ")
+}
+
+func nftablesExprs(rule *Rule) ([]expr.Any, error) {
+	nfproto, srcOffset, dstOffset := byte(unix.NFPROTO_IPV4), uint32(12), uint32(16)
+	if rule.Family == IPv6 {
+		nfproto, srcOffset, dstOffset = unix.NFPROTO_IPV6, 8, 24
+	}
+
+	exprs := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{nfproto}},
+	}
+
+	if rule.Source != "" {
+		match, err := matchNetwork(rule.Source, srcOffset)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, match...)
+	}
+
+	if rule.Destination != "" {
+		match, err := matchNetwork(rule.Destination, dstOffset)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, match...)
+	}
+
+	if rule.Port != "" {
+		port, err := strconv.ParseUint(rule.Port, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		proto := byte(unix.IPPROTO_TCP)
+		if rule.Protocol == "udp" {
+			proto = unix.IPPROTO_UDP
+		}
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{proto}},
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(uint16(port))},
+		)
+	}
+
+	if rule.OutInterface != "" {
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ifname(rule.OutInterface)},
+		)
+	}
+
+	switch rule.Verdict {
+	case Accept:
+		exprs = append(exprs, &expr.Verdict{Kind: expr.VerdictAccept})
+	case Reject:
+		exprs = append(exprs, &expr.Reject{Type: unix.NFT_REJECT_ICMPX_UNREACH, Code: unix.NFT_REJECT_ICMPX_PORT_UNREACH})
+	case Masquerade:
+		exprs = append(exprs, &expr.Masq{})
+	default:
+		return nil, fmt.Errorf("unknown verdict '%s'", rule.Verdict)
+	}
+
+	return exprs, nil
+}
+
+// matchNetwork matches the address at offset in the network
+// header against a CIDR.
+func matchNetwork(cidr string, offset uint32) ([]expr.Any, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := network.IP.To4()
+	if addr == nil {
+		addr = network.IP.To16()
+	}
+	length := uint32(len(addr))
+
+	return []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: offset, Len: length},
+		&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: length, Mask: network.Mask, Xor: make([]byte, length)},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: addr},
+	}, nil
+}
+
+// ifname pads an interface name the way the kernel stores it.
+func ifname(name string) []byte {
+	b := make([]byte, unix.IFNAMSIZ)
+	copy(b, name)
+	return b
+}
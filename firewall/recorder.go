@@ -0,0 +1,58 @@
+package firewall
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is a backend that keeps the rulesets it's given instead
+// of touching the host, to check the generated rules without root.
+type Recorder struct {
+	mu       sync.Mutex
+	applied  []*Ruleset
+	tornDown [][]Family
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Apply(ctx context.Context, rules *Ruleset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = append(r.applied, rules)
+	return nil
+}
+
+func (r *Recorder) Teardown(ctx context.Context, families []Family) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tornDown = append(r.tornDown, families)
+	return nil
+}
+
+// Commands lists the rules one per line.
+func (r *Recorder) Commands(rules *Ruleset) []string {
+	commands := []string{}
+	for _, rule := range rules.Forward {
+		commands = append(commands, "forward "+rule.String())
+	}
+	for _, rule := range rules.Postrouting {
+		commands = append(commands, "postrouting "+rule.String())
+	}
+	return commands
+}
+
+// Applied returns the rulesets applied so far, oldest first.
+func (r *Recorder) Applied() []*Ruleset {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Ruleset{}, r.applied...)
+}
+
+// TornDown returns the families of every teardown so far.
+func (r *Recorder) TornDown() [][]Family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]Family{}, r.tornDown...)
+}
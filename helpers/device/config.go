@@ -0,0 +1,87 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+)
+
+// clientConfigTemplate mirrors the config file the website
+// builds in AddDevice.tsx so that API and CLI clients get
+// exactly the same result.
+var clientConfigTemplate = template.Must(template.New("client").Parse(`[Interface]
+PrivateKey = {{ .PrivateKey }}
+Address = {{ .Address }}
+{{- if .DNS }}
+DNS = {{ .DNS }}
+{{- end }}
+
+[Peer]
+PublicKey = {{ .ServerPublicKey }}
+{{- if .PresharedKey }}
+PresharedKey = {{ .PresharedKey }}
+{{- end }}
+AllowedIPs = {{ .AllowedIPs }}
+Endpoint = {{ .Endpoint }}
+{{- if .PersistentKeepalive }}
+PersistentKeepalive = {{ .PersistentKeepalive }}
+{{- end }}
+`))
+
+// ClientConfig holds the values rendered into a
+// WireGuard client config file.
+type ClientConfig struct {
+	PrivateKey          string
+	Address             string
+	DNS                 string
+	ServerPublicKey     string
+	PresharedKey        string
+	AllowedIPs          string
+	Endpoint            string
+	PersistentKeepalive uint32
+}
+
+func (c *ClientConfig) String() string {
+	buf := bytes.Buffer{}
+	// the template only reads plain fields so it can't fail
+	_ = clientConfigTemplate.Execute(&buf, c)
+	return buf.String()
+}
+
+// ClientConfig builds the client config for a device using the
+// same server details that VPNServer.Info exposes to the website.
+func (dh *DeviceHelpers) ClientConfig(ctx context.Context, device *model.Device, privateKey string, keepalive uint32) (*ClientConfig, error) {
+	publicKey, err := dh.Wg.PublicKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get server public key")
+	}
+
+	presharedKey, err := device.GetPresharedKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt preshared key")
+	}
+
+	cfg := &ClientConfig{
+		PrivateKey:          privateKey,
+		Address:             device.Address.String(),
+		ServerPublicKey:     publicKey,
+		PresharedKey:        presharedKey,
+		AllowedIPs:          strings.Join(config.Spec.VPN.AllowedIPs, ", "),
+		Endpoint:            net.JoinHostPort(config.Spec.ExternalHost, strconv.Itoa(config.Spec.WG.Port)),
+		PersistentKeepalive: keepalive,
+	}
+
+	if config.Spec.DNS.Enabled {
+		cfg.DNS = ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP.String()
+	}
+
+	return cfg, nil
+}
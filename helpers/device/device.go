@@ -3,31 +3,37 @@ package device
 import (
 	"context"
 	"fmt"
-	"net"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/place1/wg-embed/pkg/wgembed"
-	"github.com/waas-app/WaaS/config"
 	"github.com/waas-app/WaaS/datastore"
-	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/ipam"
 	"github.com/waas-app/WaaS/model"
 	"github.com/waas-app/WaaS/util"
 	"go.uber.org/zap"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"gorm.io/gorm"
 )
 
+// how often leaked ip allocations are cleaned up
+const reconcileInterval = 10 * time.Minute
+
+var ErrDeviceExists = errors.New("a device with this name already exists")
+
 type DeviceHelpers struct {
-	Wg          wgembed.WireGuardInterface
-	deviceStore datastore.DeviceStore
-	nextIPLock  sync.Mutex
+	Wg            wgembed.WireGuardInterface
+	deviceStore   datastore.DeviceStore
+	peerRuleStore datastore.PeerRuleStore
+	pools         []*ipam.Pool
 }
 
 func NewDeviceHelpers(wg wgembed.WireGuardInterface) *DeviceHelpers {
 	return &DeviceHelpers{
-		Wg:          wg,
-		deviceStore: datastore.NewDeviceStore(),
-		nextIPLock:  sync.Mutex{},
+		Wg:            wg,
+		deviceStore:   datastore.NewDeviceStore(),
+		peerRuleStore: datastore.NewPeerRuleStore(),
+		pools:         ipam.ConfiguredPools(),
 	}
 }
 
@@ -38,18 +44,37 @@ func (dh *DeviceHelpers) RunSync(ctx context.Context) error {
 	}
 
 	go syncMetadata(ctx, dh)
+	go reconcileAllocations(ctx, dh)
 
 	return nil
 }
 
-func (dh *DeviceHelpers) AddDevice(ctx context.Context, user *model.User, name string, publicKey string) (*model.Device, error) {
+// AddDevice stores a new device for the user. presharedKey is optional
+// and stored encrypted.
+func (dh *DeviceHelpers) AddDevice(ctx context.Context, user *model.User, name string, publicKey string, presharedKey string) (*model.Device, error) {
 	if name == "" {
 		return nil, errors.New("device name must not be empty")
 	}
 
-	clientAddr, err := dh.nextClientAddress(ctx)
+	if presharedKey != "" {
+		if _, err := wgtypes.ParseKey(presharedKey); err != nil {
+			return nil, errors.Wrap(err, "invalid preshared key")
+		}
+	}
+
+	// the existing device would be overwritten
+	// and its addresses leaked
+	exists, err := dh.DeviceExists(ctx, user.Slug, name)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate an ip address for device")
+		return nil, err
+	}
+	if exists {
+		return nil, ErrDeviceExists
+	}
+
+	clientAddr, err := dh.allocateAddresses(ctx, user.Slug, name)
+	if err != nil {
+		return nil, err
 	}
 
 	device := &model.Device{
@@ -62,17 +87,63 @@ func (dh *DeviceHelpers) AddDevice(ctx context.Context, user *model.User, name s
 		CreatedAt:  time.Now(),
 	}
 
-	if err := dh.SaveDevice(ctx, device); err != nil {
+	if err := device.PutPresharedKey(presharedKey); err != nil {
+		dh.releaseAddresses(ctx, clientAddr)
+		return nil, errors.Wrap(err, "failed to encrypt preshared key")
+	}
+
+	if err := dh.deviceStore.Create(ctx, device); err != nil {
+		dh.releaseAddresses(ctx, clientAddr)
 		return nil, errors.Wrap(err, "failed to save the new device")
 	}
 
 	return device, nil
 }
 
+// GenerateDevice adds a device using a keypair generated on the server.
+// The private key is returned to the caller but never stored.
+func (dh *DeviceHelpers) GenerateDevice(ctx context.Context, user *model.User, name string, withPresharedKey bool) (*model.Device, string, error) {
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to generate a private key")
+	}
+
+	var presharedKey string
+	if withPresharedKey {
+		if presharedKey, err = GeneratePresharedKey(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	device, err := dh.AddDevice(ctx, user, name, key.PublicKey().String(), presharedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return device, key.String(), nil
+}
+
+func GeneratePresharedKey() (string, error) {
+	key, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate a preshared key")
+	}
+	return key.String(), nil
+}
+
 func (dh *DeviceHelpers) SaveDevice(ctx context.Context, device *model.Device) error {
 	return dh.deviceStore.Save(ctx, device)
 }
 
+// DeviceExists reports whether the owner has a device with the name.
+func (dh *DeviceHelpers) DeviceExists(ctx context.Context, owner string, name string) (bool, error) {
+	_, err := dh.deviceStore.Get(ctx, owner, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (dh *DeviceHelpers) ListAllDevices(ctx context.Context) ([]*model.Device, error) {
 	return dh.deviceStore.List(ctx, "")
 }
@@ -81,57 +152,108 @@ func (dh *DeviceHelpers) ListDevices(ctx context.Context, user string) ([]*model
 	return dh.deviceStore.List(ctx, user)
 }
 
-func (dh *DeviceHelpers) nextClientAddress(ctx context.Context) (string, error) {
-	dh.nextIPLock.Lock()
-	defer dh.nextIPLock.Unlock()
-
-	devices, err := dh.ListDevices(ctx, "")
+func (dh *DeviceHelpers) DeleteDevice(ctx context.Context, user string, name string) error {
+	device, err := dh.deviceStore.Get(ctx, user, name)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to list devices")
+		return errors.Wrap(err, "failed to retrieve device")
 	}
 
-	vpnip, vpnsubnet := ip.ParseCIDR(config.Spec.VPN.CIDR)
-	ipaddr := vpnip.Mask(vpnsubnet.Mask)
+	if err := dh.deviceStore.Delete(ctx, device); err != nil {
+		return err
+	}
 
-	usedIPs := []net.IP{
-		ipaddr,            // x.x.x.0
-		ip.NextIP(ipaddr), // x.x.x.1
+	for _, pool := range dh.pools {
+		if err := pool.Release(ctx, device.Owner, device.Name); err != nil {
+			return errors.Wrap(err, "failed to release device ip address")
+		}
 	}
 
-	for _, device := range devices {
-		ip, _ := ip.ParseCIDR(device.Address)
-		usedIPs = append(usedIPs, ip)
+	return nil
+}
+
+// PoolUsage reports how many addresses of the VPN subnets are allocated.
+func (dh *DeviceHelpers) PoolUsage(ctx context.Context) ([]*ipam.Usage, error) {
+	usages := []*ipam.Usage{}
+	for _, pool := range dh.pools {
+		usage, err := pool.Usage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// ReserveAddress pins an address to a device name so the device always
+// gets that address, including when it's deleted and created again.
+// The returned change can be passed to UndoReservation.
+func (dh *DeviceHelpers) ReserveAddress(ctx context.Context, owner string, name string, address string) (*ipam.ReservationChange, error) {
+	addr, err := ipam.ParseAddress(address)
+	if err != nil {
+		return nil, err
 	}
 
-	for ipad := ipaddr; vpnsubnet.Contains(ipad); ipad = ip.NextIP(ipad) {
-		if !contains(usedIPs, ipad) {
-			return fmt.Sprintf("%s/32", ipad.String()), nil
+	for _, pool := range dh.pools {
+		if pool.Contains(addr) {
+			return pool.Reserve(ctx, addr, owner, name)
 		}
 	}
+	return nil, fmt.Errorf("%s is not in any vpn subnet", addr)
+}
 
-	return "", fmt.Errorf("there are no free IP addresses in the vpn subnet: '%s'", vpnsubnet)
+// UndoReservation reverts ReserveAddress, leaving the device's other
+// reservations alone.
+func (dh *DeviceHelpers) UndoReservation(ctx context.Context, change *ipam.ReservationChange) error {
+	return ipam.UndoReservation(ctx, change)
 }
 
-func contains(ips []net.IP, target net.IP) bool {
-	for _, ip := range ips {
-		if ip.Equal(target) {
-			return true
+// UnreserveAddresses removes every reservation held by a device name.
+func (dh *DeviceHelpers) UnreserveAddresses(ctx context.Context, owner string, name string) error {
+	for _, pool := range dh.pools {
+		if err := pool.Unreserve(ctx, owner, name); err != nil {
+			return err
 		}
 	}
-	return false
+	return nil
 }
 
-func (dh *DeviceHelpers) DeleteDevice(ctx context.Context, user string, name string) error {
-	device, err := dh.deviceStore.Get(ctx, user, name)
-	if err != nil {
-		return errors.Wrap(err, "failed to retrieve device")
+func (dh *DeviceHelpers) ListReservations(ctx context.Context) ([]*model.IPReservation, error) {
+	reservations := []*model.IPReservation{}
+	for _, pool := range dh.pools {
+		items, err := pool.Reservations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, items...)
 	}
+	return reservations, nil
+}
 
-	if err := dh.deviceStore.Delete(ctx, device); err != nil {
-		return err
+// allocateAddresses allocates one address from every pool,
+// i.e. an IPv4 address and, on dual-stack servers, an IPv6 address.
+func (dh *DeviceHelpers) allocateAddresses(ctx context.Context, owner string, name string) (model.AddressList, error) {
+	addresses := model.AddressList{}
+	for _, pool := range dh.pools {
+		address, err := pool.Allocate(ctx, owner, name)
+		if err != nil {
+			dh.releaseAddresses(ctx, addresses)
+			return nil, errors.Wrapf(err, "failed to generate an ip address for device from %s", pool.CIDR())
+		}
+		addresses = append(addresses, address)
 	}
+	return addresses, nil
+}
 
-	return nil
+// releaseAddresses frees addresses that were allocated for a device
+// that couldn't be saved. Leftovers are removed by the reconciliation.
+func (dh *DeviceHelpers) releaseAddresses(ctx context.Context, addresses model.AddressList) {
+	for _, address := range addresses {
+		for _, pool := range dh.pools {
+			if err := pool.Free(ctx, address); err != nil {
+				util.Logger(ctx).Error("Error releasing ip address", zap.Error(err), zap.String("address", address))
+			}
+		}
+	}
 }
 
 func (dh *DeviceHelpers) GetByPublicKey(ctx context.Context, publicKey string) (*model.Device, error) {
@@ -144,6 +266,10 @@ func (dh *DeviceHelpers) sync(ctx context.Context) error {
 		return errors.Wrap(err, "failed to list devices")
 	}
 
+	if err := dh.reconcile(ctx, devices); err != nil {
+		return err
+	}
+
 	peers, err := dh.Wg.ListPeers()
 	if err != nil {
 		return errors.Wrap(err, "failed to list peers")
@@ -160,7 +286,7 @@ func (dh *DeviceHelpers) sync(ctx context.Context) error {
 
 	// Add peers for all devices in storage
 	for _, device := range devices {
-		if err := dh.Wg.AddPeer(device.PublicKey, device.Address); err != nil {
+		if err := ConfigurePeer(dh.Wg, device); err != nil {
 			util.Logger(ctx).Warn("failed to remove peer during sync:", zap.String("device name:", device.Name))
 		}
 	}
@@ -176,3 +302,34 @@ func deviceListContains(devices []*model.Device, publicKey string) bool {
 	}
 	return false
 }
+
+// reconcileAllocations periodically removes the ip allocations leaked
+// by devices that failed to save or servers that crashed.
+func reconcileAllocations(ctx context.Context, dh *DeviceHelpers) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		devices, err := dh.ListAllDevices(ctx)
+		if err == nil {
+			err = dh.reconcile(ctx, devices)
+		}
+		if err != nil {
+			util.Logger(ctx).Error("Error reconciling ip allocations", zap.Error(err))
+		}
+	}
+}
+
+func (dh *DeviceHelpers) reconcile(ctx context.Context, devices []*model.Device) error {
+	for _, pool := range dh.pools {
+		if err := pool.Reconcile(ctx, devices); err != nil {
+			return errors.Wrap(err, "failed to reconcile ip allocations")
+		}
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/ipam"
 	"github.com/waas-app/WaaS/model"
 	"github.com/waas-app/WaaS/proto/proto"
 	"github.com/waas-app/WaaS/util"
@@ -26,42 +28,112 @@ func (d *DeviceSvc) AddDevice(ctx context.Context, req *proto.AddDeviceReq) (*pr
 		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
 	}
 
-	device, err := d.DeviceHelpers.AddDevice(ctx, user, req.GetName(), req.GetPublicKey())
+	presharedKey := req.GetPresharedKey()
+	generated := presharedKey == "" && req.GetGeneratePresharedKey()
+	if generated {
+		var err error
+		if presharedKey, err = GeneratePresharedKey(); err != nil {
+			grpc_zap.Extract(ctx).Error("failed to generate preshared key", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to add device")
+		}
+	}
+
+	var reservation *ipam.ReservationChange
+	if req.GetAddress() != "" {
+		if !rbac.Allowed(user, rbac.DevicesManage) {
+			return nil, status.Errorf(codes.PermissionDenied, "not authorized. only device managers can choose a device address")
+		}
+
+		// reserving would replace the reservation of the existing device
+		exists, err := d.DeviceHelpers.DeviceExists(ctx, user.Slug, req.GetName())
+		if err != nil {
+			grpc_zap.Extract(ctx).Error("failed to read device", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to add device")
+		}
+		if exists {
+			return nil, status.Errorf(codes.AlreadyExists, "a device named %s already exists", req.GetName())
+		}
+
+		if reservation, err = d.DeviceHelpers.ReserveAddress(ctx, user.Slug, req.GetName(), req.GetAddress()); err != nil {
+			grpc_zap.Extract(ctx).Error("failed to reserve address", zap.Error(err))
+			return nil, status.Errorf(codes.InvalidArgument, "failed to reserve address: %s", err)
+		}
+	}
+
+	device, err := d.DeviceHelpers.AddDevice(ctx, user, req.GetName(), req.GetPublicKey(), presharedKey)
+	if err != nil && reservation != nil {
+		// the reservation would block the address, reservations
+		// made before this request are kept
+		if err := d.DeviceHelpers.UndoReservation(ctx, reservation); err != nil {
+			grpc_zap.Extract(ctx).Error("failed to remove reservation", zap.Error(err))
+		}
+	}
+	if err == ErrDeviceExists {
+		return nil, status.Errorf(codes.AlreadyExists, "a device named %s already exists", req.GetName())
+	}
 	if err != nil {
 		grpc_zap.Extract(ctx).Error("failed to add device", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to add device")
 	}
 
-	return mapDevice(device), nil
+	res := mapDevice(device)
+	if generated {
+		res.PresharedKey = presharedKey
+	}
+	return res, nil
 }
 
-func (d *DeviceSvc) ListSpecificDeviceForUser(ctx context.Context, req *proto.ListDevicesReq) (*proto.ListDevicesRes, error) {
+func (d *DeviceSvc) GenerateDevice(ctx context.Context, req *proto.GenerateDeviceReq) (*proto.GenerateDeviceRes, error) {
 	user, ok := ctx.Value(config.CurrentUser).(*model.User)
 	if !ok {
 		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
 	}
 
-	devices, err := d.DeviceHelpers.ListDevices(ctx, user.Slug)
+	// the config would have no endpoint to connect to, check
+	// before the device is created
+	if config.Spec.ExternalHost == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "the server's external host isn't configured")
+	}
+
+	device, privateKey, err := d.DeviceHelpers.GenerateDevice(ctx, user, req.GetName(), req.GetPresharedKey())
+	if err == ErrDeviceExists {
+		return nil, status.Errorf(codes.AlreadyExists, "a device named %s already exists", req.GetName())
+	}
 	if err != nil {
-		grpc_zap.Extract(ctx).Error("failed to get device", zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "failed to get device")
+		grpc_zap.Extract(ctx).Error("failed to generate device", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to generate device")
 	}
 
-	return &proto.ListDevicesRes{
-		Items: mapDevices(devices),
+	cfg, err := d.DeviceHelpers.ClientConfig(ctx, device, privateKey, req.GetPersistentKeepalive())
+	if err != nil {
+		grpc_zap.Extract(ctx).Error("failed to render client config", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to render client config")
+	}
+
+	return &proto.GenerateDeviceRes{
+		Device:     mapDevice(device),
+		ConfigFile: cfg.String(),
 	}, nil
 }
 
-func (d *DeviceSvc) ListAllDevices(ctx context.Context, req *proto.ListAllDevicesReq) (*proto.ListAllDevicesRes, error) {
+func (d *DeviceSvc) ListSpecificDeviceForUser(ctx context.Context, req *proto.ListDevicesReq) (*proto.ListDevicesRes, error) {
 	user, ok := ctx.Value(config.CurrentUser).(*model.User)
 	if !ok {
 		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
 	}
 
-	if !user.Admin {
-		return nil, status.Errorf(codes.PermissionDenied, "not authorized")
+	devices, err := d.DeviceHelpers.ListDevices(ctx, user.Slug)
+	if err != nil {
+		grpc_zap.Extract(ctx).Error("failed to get device", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get device")
 	}
 
+	return &proto.ListDevicesRes{
+		Items: mapDevices(devices),
+	}, nil
+}
+
+func (d *DeviceSvc) ListAllDevices(ctx context.Context, req *proto.ListAllDevicesReq) (*proto.ListAllDevicesRes, error) {
 	devices, err := d.DeviceHelpers.ListAllDevices(ctx)
 	if err != nil {
 		grpc_zap.Extract(ctx).Error("failed to get device", zap.Error(err))
@@ -79,11 +151,12 @@ func (d *DeviceSvc) DeleteDevice(ctx context.Context, req *proto.DeleteDeviceReq
 		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
 	}
 
-	if !user.Admin {
-		return nil, status.Errorf(codes.PermissionDenied, "not authorized. only admins can delete devices")
+	owner := user.Slug
+	if req.GetOwner() != nil {
+		owner = req.GetOwner().GetValue()
 	}
 
-	err := d.DeviceHelpers.DeleteDevice(ctx, user.Slug, req.GetName())
+	err := d.DeviceHelpers.DeleteDevice(ctx, owner, req.GetName())
 	if err != nil {
 		grpc_zap.Extract(ctx).Error("failed to delete device", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to delete device")
@@ -99,7 +172,8 @@ func mapDevice(d *model.Device) *proto.Device {
 		OwnerName:         d.OwnerName,
 		OwnerEmail:        d.OwnerEmail,
 		PublicKey:         d.PublicKey,
-		Address:           d.Address,
+		Address:           d.Address.String(),
+		Addresses:         d.Address,
 		CreatedAt:         util.TimeToTimestamp(&d.CreatedAt),
 		LastHandshakeTime: util.TimeToTimestamp(d.LastHandshakeTime),
 		ReceiveBytes:      d.ReceiveBytes,
@@ -130,3 +204,96 @@ func isConnected(lastHandshake *time.Time) bool {
 	}
 	return lastHandshake.After(time.Now().Add(-3 * time.Minute))
 }
+
+func (d *DeviceSvc) AddPeerRule(ctx context.Context, req *proto.AddPeerRuleReq) (*proto.PeerRule, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	rule := &model.PeerRule{
+		Owner: user.Slug,
+	}
+	if req.GetOwner() != nil {
+		rule.Owner = req.GetOwner().GetValue()
+	}
+
+	if !req.GetSameOwner() {
+		rule.Device = req.GetDevice()
+		rule.PeerDevice = req.GetPeerDevice()
+		rule.PeerOwner = rule.Owner
+		if req.GetPeerOwner() != nil {
+			rule.PeerOwner = req.GetPeerOwner().GetValue()
+		}
+	}
+
+	if !rbac.Allowed(user, rbac.DevicesManage) && (rule.Owner != user.Slug || (rule.PeerOwner != "" && rule.PeerOwner != user.Slug)) {
+		return nil, status.Errorf(codes.PermissionDenied, "not authorized. only device managers can link devices of other users")
+	}
+
+	if err := d.DeviceHelpers.AddPeerRule(ctx, rule); err != nil {
+		grpc_zap.Extract(ctx).Error("failed to add peer rule", zap.Error(err))
+		return nil, status.Errorf(codes.InvalidArgument, "failed to add peer rule: %s", err)
+	}
+
+	return mapPeerRule(rule), nil
+}
+
+func (d *DeviceSvc) ListPeerRules(ctx context.Context, req *proto.ListPeerRulesReq) (*proto.ListPeerRulesRes, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	rules, err := d.DeviceHelpers.ListPeerRules(ctx)
+	if err != nil {
+		grpc_zap.Extract(ctx).Error("failed to list peer rules", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list peer rules")
+	}
+
+	items := []*proto.PeerRule{}
+	for _, rule := range rules {
+		if rbac.Allowed(user, rbac.DevicesRead) || rule.Involves(user.Slug) {
+			items = append(items, mapPeerRule(rule))
+		}
+	}
+
+	return &proto.ListPeerRulesRes{
+		Items: items,
+	}, nil
+}
+
+func (d *DeviceSvc) DeletePeerRule(ctx context.Context, req *proto.DeletePeerRuleReq) (*empty.Empty, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	rule, err := d.DeviceHelpers.GetPeerRule(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "peer rule not found")
+	}
+
+	if !rbac.Allowed(user, rbac.DevicesManage) && !rule.Involves(user.Slug) {
+		return nil, status.Errorf(codes.PermissionDenied, "not authorized")
+	}
+
+	if err := d.DeviceHelpers.DeletePeerRule(ctx, rule); err != nil {
+		grpc_zap.Extract(ctx).Error("failed to delete peer rule", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to delete peer rule")
+	}
+
+	return &empty.Empty{}, nil
+}
+
+func mapPeerRule(r *model.PeerRule) *proto.PeerRule {
+	return &proto.PeerRule{
+		Id:         uint64(r.ID),
+		Owner:      r.Owner,
+		Device:     r.Device,
+		PeerOwner:  r.PeerOwner,
+		PeerDevice: r.PeerDevice,
+		SameOwner:  r.SameOwner(),
+		CreatedAt:  util.TimeToTimestamp(&r.CreatedAt),
+	}
+}
@@ -0,0 +1,195 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/firewall"
+	"github.com/waas-app/WaaS/infra/red"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+// how often the firewall is rebuilt from the
+// database in case a device event was missed
+const firewallResyncInterval = 5 * time.Minute
+
+// firewallMu keeps rebuilds from applying stale
+// rules over each other
+var firewallMu sync.Mutex
+
+// ConfigureFirewall rebuilds the forwarding rules, including the per-device
+// rules of the configured network policies. Device events are published
+// before the change is committed, so the event's device is merged into or
+// removed from the stored devices.
+func (dh *DeviceHelpers) ConfigureFirewall(ctx context.Context, event *model.DevicePayload) error {
+	firewallMu.Lock()
+	defer firewallMu.Unlock()
+	// the server is shutting down and may have
+	// removed the rules already
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	backend, err := firewall.New(config.Spec.VPN.Firewall)
+	if err != nil {
+		return err
+	}
+
+	opts, err := dh.firewallOptions(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	util.Logger(ctx).Debug("Configuring firewall", zap.String("backend", config.Spec.VPN.Firewall), zap.Int("acls", len(opts.ACLs)), zap.Int("peerLinks", len(opts.PeerLinks)))
+	return firewall.Configure(ctx, backend, *opts)
+}
+
+// RunFirewallSync rebuilds the firewall on device events so that the
+// policies and peer rules cover devices created after startup, with a
+// periodic rebuild as a fallback.
+func (dh *DeviceHelpers) RunFirewallSync(ctx context.Context) {
+	listener := func(ctx context.Context, msg *red.Message) error {
+		payload := new(model.DevicePayload)
+		if err := json.Unmarshal([]byte(msg.Payload), payload); err != nil {
+			return err
+		}
+		return dh.ConfigureFirewall(ctx, payload)
+	}
+
+	// the periodic rebuild still picks up new
+	// devices when redis isn't available
+	if err := red.Listen(ctx, listener, config.DevicesCreate, config.DevicesDelete); err != nil {
+		util.Logger(ctx).Warn("Not listening for device events", zap.Error(err))
+	}
+
+	go func() {
+		ticker := time.NewTicker(firewallResyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := dh.ConfigureFirewall(ctx, nil); err != nil {
+					util.Logger(ctx).Error("Error configuring firewall", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// PlanFirewall returns the rules ConfigureFirewall would apply
+// without touching the host.
+func (dh *DeviceHelpers) PlanFirewall(ctx context.Context) (*firewall.Ruleset, error) {
+	opts, err := dh.firewallOptions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return firewall.Plan(*opts), nil
+}
+
+// TeardownFirewall removes the rules and chains installed by ConfigureFirewall.
+func (dh *DeviceHelpers) TeardownFirewall(ctx context.Context) error {
+	firewallMu.Lock()
+	defer firewallMu.Unlock()
+
+	backend, err := firewall.New(config.Spec.VPN.Firewall)
+	if err != nil {
+		return err
+	}
+
+	families := []firewall.Family{firewall.IPv4}
+	if config.Spec.VPN.CIDRv6 != "" {
+		families = append(families, firewall.IPv6)
+	}
+	return backend.Teardown(ctx, families)
+}
+
+func (dh *DeviceHelpers) firewallOptions(ctx context.Context, event *model.DevicePayload) (*firewall.Options, error) {
+	devices, err := dh.ListAllDevices(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list devices")
+	}
+
+	if event != nil && event.Device != nil {
+		filtered := make([]*model.Device, 0, len(devices))
+		for _, device := range devices {
+			if device.PublicKey != event.Device.PublicKey {
+				filtered = append(filtered, device)
+			}
+		}
+		if event.Type == config.DevicesCreate {
+			filtered = append(filtered, event.Device)
+		}
+		devices = filtered
+	}
+
+	acls, err := dh.compileACLs(ctx, devices)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := dh.peerLinks(ctx, devices)
+	if err != nil {
+		return nil, err
+	}
+
+	cidrs := []string{config.Spec.VPN.CIDR}
+	allowedIPs := append([]string{}, config.Spec.VPN.AllowedIPs...)
+	allowedIPs = appendMissing(allowedIPs, fmt.Sprintf("%s/32", ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP))
+	if config.Spec.VPN.CIDRv6 != "" {
+		cidrs = append(cidrs, config.Spec.VPN.CIDRv6)
+		allowedIPs = appendMissing(allowedIPs, fmt.Sprintf("%s/128", ip.GetWireGuardServerIP(config.Spec.VPN.CIDRv6).IP))
+	}
+
+	return &firewall.Options{
+		CIDRs:            cidrs,
+		AllowedIPs:       allowedIPs,
+		GatewayInterface: config.Spec.VPN.GatewayInterface,
+		ACLs:             acls,
+		ClientIsolation:  config.Spec.VPN.ClientIsolation,
+		PeerLinks:        links,
+	}, nil
+}
+
+func (dh *DeviceHelpers) compileACLs(ctx context.Context, devices []*model.Device) ([]*firewall.ACL, error) {
+	if len(config.Spec.VPN.Policies) == 0 {
+		return nil, nil
+	}
+
+	slugs := []string{}
+	for _, device := range devices {
+		slugs = append(slugs, device.Owner)
+	}
+
+	users := map[string]*model.User{}
+	if len(slugs) > 0 {
+		found, err := datastore.NewUserStore().FindByQuery(ctx, "slug IN ?", slugs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load device owners")
+		}
+		for _, user := range found {
+			users[user.Slug] = user
+		}
+	}
+
+	return firewall.CompileACLs(config.Spec.VPN.Policies, devices, users)
+}
+
+func appendMissing(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,100 @@
+package device
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/firewall"
+	"github.com/waas-app/WaaS/model"
+	"gorm.io/gorm"
+)
+
+// fakeDeviceStore lists the devices it was created with.
+type fakeDeviceStore struct {
+	devices []*model.Device
+}
+
+func (s *fakeDeviceStore) Save(ctx context.Context, device *model.Device) error   { return nil }
+func (s *fakeDeviceStore) Create(ctx context.Context, device *model.Device) error { return nil }
+func (s *fakeDeviceStore) Delete(ctx context.Context, device *model.Device) error { return nil }
+
+func (s *fakeDeviceStore) List(ctx context.Context, owner string) ([]*model.Device, error) {
+	return s.devices, nil
+}
+
+func (s *fakeDeviceStore) Get(ctx context.Context, owner string, name string) (*model.Device, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *fakeDeviceStore) GetByPublicKey(ctx context.Context, publicKey string) (*model.Device, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakePeerRuleStore lists the rules it was created with.
+type fakePeerRuleStore struct {
+	rules []*model.PeerRule
+}
+
+func (s *fakePeerRuleStore) Save(ctx context.Context, rule *model.PeerRule) error   { return nil }
+func (s *fakePeerRuleStore) Delete(ctx context.Context, rule *model.PeerRule) error { return nil }
+
+func (s *fakePeerRuleStore) List(ctx context.Context) ([]*model.PeerRule, error) {
+	return s.rules, nil
+}
+
+func (s *fakePeerRuleStore) Get(ctx context.Context, id uint) (*model.PeerRule, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestFirewallOptionsDeviceEvents(t *testing.T) {
+	spec := config.Spec
+	defer func() { config.Spec = spec }()
+	config.Spec.VPN.CIDR = "10.0.0.0/24"
+	config.Spec.VPN.CIDRv6 = ""
+	config.Spec.VPN.ClientIsolation = true
+	config.Spec.VPN.Policies = nil
+
+	laptop := &model.Device{Owner: "alice", Name: "laptop", PublicKey: "laptop", Address: []string{"10.0.0.2/32"}}
+	phone := &model.Device{Owner: "alice", Name: "phone", PublicKey: "phone", Address: []string{"10.0.0.3/32"}}
+	printer := &model.Device{Owner: "bob", Name: "printer", PublicKey: "printer", Address: []string{"10.0.0.4/32"}}
+
+	dh := &DeviceHelpers{
+		deviceStore: &fakeDeviceStore{devices: []*model.Device{laptop, printer}},
+		peerRuleStore: &fakePeerRuleStore{rules: []*model.PeerRule{
+			{Owner: "alice"},
+			{Owner: "alice", Device: "phone", PeerOwner: "bob", PeerDevice: "printer"},
+		}},
+	}
+
+	for name, test := range map[string]struct {
+		event *model.DevicePayload
+		links []*firewall.PeerLink
+	}{
+		"stored devices": {
+			links: []*firewall.PeerLink{},
+		},
+		"created device": {
+			event: &model.DevicePayload{Type: config.DevicesCreate, Device: phone},
+			links: []*firewall.PeerLink{
+				{A: []string{"10.0.0.2/32", "10.0.0.3/32"}, B: []string{"10.0.0.2/32", "10.0.0.3/32"}},
+				{A: []string{"10.0.0.3/32"}, B: []string{"10.0.0.4/32"}},
+			},
+		},
+		"deleted device": {
+			event: &model.DevicePayload{Type: config.DevicesDelete, Device: printer},
+			links: []*firewall.PeerLink{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts, err := dh.firewallOptions(context.Background(), test.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(opts.PeerLinks, test.links) {
+				t.Errorf("got peer links %+v, want %+v", opts.PeerLinks, test.links)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package device
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/place1/wg-embed/pkg/wgembed"
+	"github.com/waas-app/WaaS/model"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// namedInterface is implemented by wgembed interfaces that
+// are backed by a real WireGuard device.
+type namedInterface interface {
+	Name() string
+}
+
+// ConfigurePeer adds or updates the WireGuard peer for a device.
+// wgembed's AddPeer can only set a single address and no preshared
+// key so the peer is configured through wgctrl directly.
+func ConfigurePeer(wg wgembed.WireGuardInterface, device *model.Device) error {
+	if len(device.Address) == 0 {
+		return errors.New("device has no address")
+	}
+
+	named, ok := wg.(namedInterface)
+	if !ok {
+		return wg.AddPeer(device.PublicKey, device.Address[0])
+	}
+
+	publicKey, err := wgtypes.ParseKey(device.PublicKey)
+	if err != nil {
+		return errors.Wrapf(err, "bad public key %v", device.PublicKey)
+	}
+
+	peer := wgtypes.PeerConfig{
+		PublicKey:         publicKey,
+		ReplaceAllowedIPs: true,
+	}
+
+	psk, err := device.GetPresharedKey()
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt preshared key")
+	}
+
+	if psk != "" {
+		presharedKey, err := wgtypes.ParseKey(psk)
+		if err != nil {
+			return errors.Wrap(err, "bad preshared key")
+		}
+		peer.PresharedKey = &presharedKey
+	}
+
+	for _, address := range device.Address {
+		_, allowedIP, err := net.ParseCIDR(address)
+		if err != nil {
+			return errors.Wrap(err, "bad CIDR value for AllowedIPs")
+		}
+		peer.AllowedIPs = append(peer.AllowedIPs, *allowedIP)
+	}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to create wg client")
+	}
+	defer client.Close()
+
+	return client.ConfigureDevice(named.Name(), wgtypes.Config{
+		ReplacePeers: false,
+		Peers:        []wgtypes.PeerConfig{peer},
+	})
+}
@@ -0,0 +1,106 @@
+package device
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/firewall"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+// AddPeerRule stores a rule letting devices reach each other when
+// client isolation is enabled and re-applies the firewall.
+func (dh *DeviceHelpers) AddPeerRule(ctx context.Context, rule *model.PeerRule) error {
+	if !rule.SameOwner() {
+		if rule.Device == "" || rule.PeerOwner == "" || rule.PeerDevice == "" {
+			return errors.New("a peer rule needs two devices")
+		}
+		if rule.Owner == rule.PeerOwner && rule.Device == rule.PeerDevice {
+			return errors.New("a device can't be its own peer")
+		}
+		if _, err := dh.deviceStore.Get(ctx, rule.Owner, rule.Device); err != nil {
+			return fmt.Errorf("device '%s' does not exist", rule.Device)
+		}
+		if _, err := dh.deviceStore.Get(ctx, rule.PeerOwner, rule.PeerDevice); err != nil {
+			return fmt.Errorf("device '%s' does not exist", rule.PeerDevice)
+		}
+	}
+
+	if err := dh.peerRuleStore.Save(ctx, rule); err != nil {
+		return errors.Wrap(err, "failed to save peer rule")
+	}
+	return dh.reconfigureFirewall(ctx)
+}
+
+func (dh *DeviceHelpers) GetPeerRule(ctx context.Context, id uint) (*model.PeerRule, error) {
+	return dh.peerRuleStore.Get(ctx, id)
+}
+
+func (dh *DeviceHelpers) ListPeerRules(ctx context.Context) ([]*model.PeerRule, error) {
+	return dh.peerRuleStore.List(ctx)
+}
+
+func (dh *DeviceHelpers) DeletePeerRule(ctx context.Context, rule *model.PeerRule) error {
+	if err := dh.peerRuleStore.Delete(ctx, rule); err != nil {
+		return errors.Wrap(err, "failed to delete peer rule")
+	}
+	return dh.reconfigureFirewall(ctx)
+}
+
+// reconfigureFirewall applies rule changes that aren't
+// announced through device events.
+func (dh *DeviceHelpers) reconfigureFirewall(ctx context.Context) error {
+	if !config.Spec.WG.Enabled {
+		return nil
+	}
+	if err := dh.ConfigureFirewall(ctx, nil); err != nil {
+		util.Logger(ctx).Error("Error configuring firewall", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// peerLinks resolves the stored peer rules to the addresses of the devices.
+// Rules for devices that don't exist right now are skipped.
+func (dh *DeviceHelpers) peerLinks(ctx context.Context, devices []*model.Device) ([]*firewall.PeerLink, error) {
+	if !config.Spec.VPN.ClientIsolation {
+		return nil, nil
+	}
+
+	rules, err := dh.peerRuleStore.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list peer rules")
+	}
+
+	byName := map[string]*model.Device{}
+	byOwner := map[string][]string{}
+	for _, device := range devices {
+		byName[device.Owner+"/"+device.Name] = device
+		byOwner[device.Owner] = append(byOwner[device.Owner], device.Address...)
+	}
+
+	links := []*firewall.PeerLink{}
+	for _, rule := range rules {
+		if rule.SameOwner() {
+			if addresses := byOwner[rule.Owner]; len(addresses) > 1 {
+				links = append(links, &firewall.PeerLink{A: addresses, B: addresses})
+			}
+			continue
+		}
+
+		device, ok := byName[rule.Owner+"/"+rule.Device]
+		if !ok {
+			continue
+		}
+		peer, ok := byName[rule.PeerOwner+"/"+rule.PeerDevice]
+		if !ok {
+			continue
+		}
+		links = append(links, &firewall.PeerLink{A: device.Address, B: peer.Address})
+	}
+	return links, nil
+}
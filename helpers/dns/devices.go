@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/red"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+// how often the devices are reloaded from the
+// database in case a device event was missed
+const deviceResyncInterval = 5 * time.Minute
+
+// DeviceSet is in-memory state of the DNS server that
+// follows the VPN devices, e.g. the zone.
+type DeviceSet interface {
+	Replace(devices []*model.Device)
+	SetDevice(device *model.Device)
+	RemoveDevice(device *model.Device)
+}
+
+// RunDeviceSync loads the devices into the sets and keeps them up to date
+// from the device events, with a periodic reload as a fallback.
+func RunDeviceSync(ctx context.Context, sets ...DeviceSet) error {
+	if err := loadDevices(ctx, sets); err != nil {
+		return err
+	}
+
+	listener := func(ctx context.Context, msg *red.Message) error {
+		payload := new(model.DevicePayload)
+		if err := json.Unmarshal([]byte(msg.Payload), payload); err != nil {
+			return err
+		}
+		if payload.Device == nil {
+			return nil
+		}
+
+		for _, set := range sets {
+			switch payload.Type {
+			case config.DevicesCreate:
+				set.SetDevice(payload.Device)
+			case config.DevicesDelete:
+				set.RemoveDevice(payload.Device)
+			}
+		}
+		return nil
+	}
+
+	// the periodic reload still keeps the sets
+	// reasonably fresh when redis isn't available
+	if err := red.Listen(ctx, listener, config.DevicesCreate, config.DevicesDelete); err != nil {
+		util.Logger(ctx).Warn("Not listening for device events", zap.Error(err))
+	}
+
+	go func() {
+		ticker := time.NewTicker(deviceResyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := loadDevices(ctx, sets); err != nil {
+					util.Logger(ctx).Error("Error reloading DNS devices", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func loadDevices(ctx context.Context, sets []DeviceSet) error {
+	devices, err := datastore.NewDeviceStore().List(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		set.Replace(devices)
+	}
+	util.Logger(ctx).Debug("Loaded DNS devices", zap.Int("devices", len(devices)))
+	return nil
+}
@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"context"
+	"time"
+
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/proto/proto"
+	"github.com/waas-app/WaaS/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DNSSvc is a gRPC service that reports on the embedded DNS server.
+type DNSSvc struct {
+	// Server is nil when the DNS server is disabled
+	Server *ip.DNSServer
+}
+
+func (d *DNSSvc) Stats(ctx context.Context, req *proto.DNSStatsReq) (*proto.DNSStatsRes, error) {
+	if d.Server == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "dns is disabled")
+	}
+
+	cache := d.Server.CacheStats()
+	res := &proto.DNSStatsRes{
+		Cache: &proto.CacheStats{
+			Entries:    uint64(cache.Entries),
+			Hits:       cache.Hits,
+			Misses:     cache.Misses,
+			Prefetches: cache.Prefetches,
+		},
+	}
+	for _, stats := range d.Server.Stats() {
+		res.Upstreams = append(res.Upstreams, &proto.UpstreamStats{
+			Address:      stats.Address,
+			Healthy:      stats.Healthy,
+			Queries:      stats.Queries,
+			Failures:     stats.Failures,
+			AvgLatencyMs: float64(stats.AvgLatency) / float64(time.Millisecond),
+			LastError:    stats.LastError,
+			Zone:         stats.Zone,
+		})
+	}
+	return res, nil
+}
+
+// how many queries SearchQueries returns without a limit
+const defaultQueryLimit = 100
+
+func (d *DNSSvc) SearchQueries(ctx context.Context, req *proto.SearchQueriesReq) (*proto.SearchQueriesRes, error) {
+	if d.Server == nil || d.Server.QueryLog() == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "dns query log is disabled")
+	}
+
+	search := &ip.QuerySearch{
+		Device: req.Device,
+		User:   req.User,
+		Domain: req.Domain,
+		Limit:  int(req.Limit),
+	}
+	if req.From != nil {
+		search.From = util.TimestampToTime(req.From)
+	}
+	if req.To != nil {
+		search.To = util.TimestampToTime(req.To)
+	}
+	if search.Limit <= 0 {
+		search.Limit = defaultQueryLimit
+	}
+
+	res := &proto.SearchQueriesRes{}
+	for _, q := range d.Server.QueryLog().Search(search) {
+		res.Queries = append(res.Queries, &proto.DNSQuery{
+			Time:       util.TimeToTimestamp(&q.Time),
+			Client:     q.Client,
+			Name:       q.Name,
+			Type:       q.Type,
+			Rcode:      q.Rcode,
+			LatencyMs:  float64(q.Latency) / float64(time.Millisecond),
+			Cached:     q.Cached,
+			Blocked:    q.Blocked,
+			Device:     q.Device,
+			Owner:      q.Owner,
+			OwnerEmail: q.OwnerEmail,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,234 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/red"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+const defaultFilterRefresh = 24 * time.Hour
+
+// NewFilter returns the DNS filter for the configured policies,
+// or nil when filtering is disabled.
+func NewFilter() (*ip.Filter, error) {
+	filter := config.Spec.DNS.Filter
+	if len(filter.Policies) == 0 {
+		return nil, nil
+	}
+
+	// devices are mapped to their policy by name
+	names := map[string]bool{}
+	for _, policy := range filter.Policies {
+		if policy.Name == "" {
+			return nil, errors.New("dns filter policies need a name")
+		}
+		if names[policy.Name] {
+			return nil, fmt.Errorf("duplicate dns filter policy '%s'", policy.Name)
+		}
+		names[policy.Name] = true
+	}
+
+	switch strings.ToLower(filter.Response) {
+	case "", "nxdomain":
+		return ip.NewFilter(false), nil
+	case "null":
+		return ip.NewFilter(true), nil
+	default:
+		return nil, fmt.Errorf("unknown dns filter response '%s'", filter.Response)
+	}
+}
+
+// filterSync keeps the filter's lists and clients up to date.
+type filterSync struct {
+	filter *ip.Filter
+	// last good copy of every list so a failed
+	// download doesn't unblock its domains
+	lists map[string]ip.DomainList
+}
+
+// RunFilterSync loads the configured lists into the filter and maps the
+// devices to their owner's policy. The lists are reloaded periodically
+// and the devices follow the device events.
+func RunFilterSync(ctx context.Context, filter *ip.Filter) error {
+	s := &filterSync{
+		filter: filter,
+		lists:  map[string]ip.DomainList{},
+	}
+
+	s.loadPolicies(ctx)
+	if err := s.loadClients(ctx, nil); err != nil {
+		return err
+	}
+
+	listener := func(ctx context.Context, msg *red.Message) error {
+		payload := new(model.DevicePayload)
+		if err := json.Unmarshal([]byte(msg.Payload), payload); err != nil {
+			return err
+		}
+		return s.loadClients(ctx, payload)
+	}
+	if err := red.Listen(ctx, listener, config.DevicesCreate, config.DevicesDelete); err != nil {
+		util.Logger(ctx).Warn("Not listening for device events", zap.Error(err))
+	}
+
+	refresh := config.Spec.DNS.Filter.Refresh
+	if refresh <= 0 {
+		refresh = defaultFilterRefresh
+	}
+
+	go func() {
+		lists := time.NewTicker(refresh)
+		defer lists.Stop()
+		clients := time.NewTicker(deviceResyncInterval)
+		defer clients.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-lists.C:
+				s.loadPolicies(ctx)
+			case <-clients.C:
+				if err := s.loadClients(ctx, nil); err != nil {
+					util.Logger(ctx).Error("Error loading DNS filter clients", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// loadPolicies (re)loads every list before swapping them in. A list
+// that fails to load keeps its previous content.
+func (s *filterSync) loadPolicies(ctx context.Context) {
+	loaded := map[string]ip.DomainList{}
+	load := func(sources []string) []ip.DomainList {
+		lists := []ip.DomainList{}
+		for _, source := range sources {
+			list, ok := loaded[source]
+			if !ok {
+				var err error
+				list, err = ip.LoadDomainList(ctx, source)
+				if err != nil {
+					util.Logger(ctx).Error("Error loading DNS filter list", zap.String("source", source), zap.Error(err))
+					list = s.lists[source]
+				}
+				loaded[source] = list
+			}
+			if list != nil {
+				lists = append(lists, list)
+			}
+		}
+		return lists
+	}
+
+	policies := []*ip.FilterPolicy{}
+	fallback := ""
+	for _, policy := range config.Spec.DNS.Filter.Policies {
+		policies = append(policies, &ip.FilterPolicy{
+			Name:  policy.Name,
+			Block: load(policy.Block),
+			Allow: load(policy.Allow),
+		})
+		if fallback == "" && len(policy.Users) == 0 && len(policy.Groups) == 0 {
+			fallback = policy.Name
+		}
+	}
+
+	s.filter.SetPolicies(policies, fallback)
+	for source, list := range loaded {
+		if list != nil {
+			s.lists[source] = list
+		}
+	}
+
+	util.Logger(ctx).Info("Loaded DNS filter lists", zap.Int("policies", len(policies)), zap.Int("lists", len(loaded)))
+}
+
+// loadClients maps the address of every device to the policy of its
+// owner. Device events are published before the change is committed,
+// so the event's device is merged into or removed from the stored ones.
+func (s *filterSync) loadClients(ctx context.Context, event *model.DevicePayload) error {
+	devices, err := datastore.NewDeviceStore().List(ctx, "")
+	if err != nil {
+		return errors.Wrap(err, "failed to list devices")
+	}
+
+	if event != nil && event.Device != nil {
+		filtered := make([]*model.Device, 0, len(devices))
+		for _, device := range devices {
+			if device.PublicKey != event.Device.PublicKey {
+				filtered = append(filtered, device)
+			}
+		}
+		if event.Type == config.DevicesCreate {
+			filtered = append(filtered, event.Device)
+		}
+		devices = filtered
+	}
+
+	slugs := []string{}
+	for _, device := range devices {
+		slugs = append(slugs, device.Owner)
+	}
+
+	users := map[string]*model.User{}
+	if len(slugs) > 0 {
+		found, err := datastore.NewUserStore().FindByQuery(ctx, "slug IN ?", slugs)
+		if err != nil {
+			return errors.Wrap(err, "failed to load device owners")
+		}
+		for _, user := range found {
+			users[user.Slug] = user
+		}
+	}
+
+	clients := map[string]string{}
+	for _, device := range devices {
+		policy := filterPolicyFor(users[device.Owner])
+		if policy == "" {
+			continue
+		}
+		for _, address := range device.Address {
+			if addr, _, err := net.ParseCIDR(address); err == nil {
+				clients[addr.String()] = policy
+			}
+		}
+	}
+
+	s.filter.SetClients(clients)
+	return nil
+}
+
+// filterPolicyFor returns the name of the first policy naming the
+// user or one of their groups. Empty means the fallback policy.
+func filterPolicyFor(user *model.User) string {
+	if user == nil {
+		return ""
+	}
+	for _, policy := range config.Spec.DNS.Filter.Policies {
+		for _, email := range policy.Users {
+			if strings.EqualFold(email, user.Email) {
+				return policy.Name
+			}
+		}
+		for _, group := range policy.Groups {
+			if user.InGroup(group) {
+				return policy.Name
+			}
+		}
+	}
+	return ""
+}
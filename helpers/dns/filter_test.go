@@ -0,0 +1,39 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/waas-app/WaaS/config"
+)
+
+func TestNewFilterPolicyNames(t *testing.T) {
+	spec := config.Spec
+	defer func() { config.Spec = spec }()
+
+	for name, test := range map[string]struct {
+		policies []config.FilterPolicy
+		valid    bool
+	}{
+		"unique names": {
+			policies: []config.FilterPolicy{{Name: "kids", Groups: []string{"kids"}}, {Name: "default"}},
+			valid:    true,
+		},
+		"unnamed policy": {
+			policies: []config.FilterPolicy{{Groups: []string{"kids"}}, {Name: "default"}},
+		},
+		"duplicate names": {
+			policies: []config.FilterPolicy{{Name: "default", Groups: []string{"kids"}}, {Name: "default"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config.Spec.DNS.Filter.Policies = test.policies
+			filter, err := NewFilter()
+			if test.valid && (err != nil || filter == nil) {
+				t.Errorf("expected a filter, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
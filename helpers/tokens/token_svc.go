@@ -0,0 +1,125 @@
+package tokens
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/proto/proto"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// how long tokens are valid when the expiry isn't set
+const defaultTokenLifetime = 90 * 24 * time.Hour
+
+// TokenSvc is a gRPC service for users to manage their API tokens.
+type TokenSvc struct{}
+
+func (t *TokenSvc) CreateToken(ctx context.Context, req *proto.CreateTokenReq) (*proto.CreateTokenRes, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	if req.GetName() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "name is required")
+	}
+	if len(req.GetScopes()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one scope is required")
+	}
+	for _, scope := range req.GetScopes() {
+		if !model.ValidScope(scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown scope %s", scope)
+		}
+		if scope == model.ScopeAdmin && !rbac.Privileged(user) {
+			return nil, status.Errorf(codes.PermissionDenied, "not authorized. only admins and users with a role can create admin tokens")
+		}
+	}
+
+	expiresAt := time.Now().Add(defaultTokenLifetime)
+	if req.GetExpiresAt() != nil {
+		expiresAt = util.TimestampToTime(req.GetExpiresAt())
+		if !expiresAt.After(time.Now()) {
+			return nil, status.Errorf(codes.InvalidArgument, "expires_at must be in the future")
+		}
+	}
+
+	secret, hash, err := Generate()
+	if err != nil {
+		grpc_zap.Extract(ctx).Error("failed to generate api token", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to create api token")
+	}
+
+	token := &model.APIToken{
+		UserID:    user.ID,
+		Name:      req.GetName(),
+		Prefix:    secret[:displayPrefixLength],
+		Hash:      hash,
+		Scopes:    req.GetScopes(),
+		ExpiresAt: expiresAt,
+	}
+	if err := datastore.NewAPITokenStore().Save(ctx, token); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create api token")
+	}
+
+	return &proto.CreateTokenRes{
+		Token:  mapToken(token),
+		Secret: secret,
+	}, nil
+}
+
+func (t *TokenSvc) ListTokens(ctx context.Context, req *proto.ListTokensReq) (*proto.ListTokensRes, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	tokens, err := datastore.NewAPITokenStore().ListForUser(ctx, user.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list api tokens")
+	}
+
+	res := &proto.ListTokensRes{}
+	for _, token := range tokens {
+		res.Items = append(res.Items, mapToken(token))
+	}
+	return res, nil
+}
+
+func (t *TokenSvc) DeleteToken(ctx context.Context, req *proto.DeleteTokenReq) (*empty.Empty, error) {
+	user, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+
+	store := datastore.NewAPITokenStore()
+	token, err := store.Get(ctx, uint(req.GetId()))
+	if err != nil || token.UserID != user.ID {
+		return nil, status.Errorf(codes.NotFound, "api token not found")
+	}
+
+	if err := store.Delete(ctx, token); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete api token")
+	}
+	return &empty.Empty{}, nil
+}
+
+func mapToken(t *model.APIToken) *proto.Token {
+	return &proto.Token{
+		Id:         uint64(t.ID),
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     t.Scopes,
+		ExpiresAt:  util.TimeToTimestamp(&t.ExpiresAt),
+		LastUsedAt: util.TimeToTimestamp(t.LastUsedAt),
+		CreatedAt:  util.TimeToTimestamp(&t.CreatedAt),
+	}
+}
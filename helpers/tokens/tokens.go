@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/model"
+)
+
+const (
+	// tokenPrefix makes tokens easy to recognize, e.g. by secret scanners
+	tokenPrefix = "waas_"
+	// how much of the token is kept to tell tokens apart
+	displayPrefixLength = len(tokenPrefix) + 6
+	// how often the last use of a token is written
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid api token")
+	ErrExpiredToken = errors.New("api token expired")
+)
+
+// Generate returns a new random token and its hash.
+func Generate() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return secret, hash(secret), nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns the token and the user it belongs to. The
+// user only keeps their admin access and roles if the token has
+// the admin scope.
+func Authenticate(ctx context.Context, secret string) (*model.User, *model.APIToken, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	token, err := datastore.NewAPITokenStore().FindByHash(ctx, hash(secret))
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if token.Expired(now) {
+		return nil, nil, ErrExpiredToken
+	}
+
+	user, err := datastore.NewUserStore().FindUserByID(ctx, token.UserID)
+	if err != nil || user.Disabled {
+		return nil, nil, ErrInvalidToken
+	}
+	if !token.Allows(model.ScopeAdmin) {
+		user = rbac.WithoutPrivileges(user)
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedInterval {
+		token.LastUsedAt = &now
+		if err := datastore.NewAPITokenStore().Save(ctx, token); err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, token, nil
+}
@@ -0,0 +1,120 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/helpers/device"
+	"github.com/waas-app/WaaS/infra/ldap"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// users created by LDAP logins
+	ldapProvider            = "ldap"
+	defaultLDAPSyncInterval = time.Hour
+)
+
+// RunLDAPSync periodically checks the LDAP users against the directory.
+// Users that left it are disabled and their devices are deleted, the
+// groups and admin flag of the others are updated.
+func RunLDAPSync(ctx context.Context, dh *device.DeviceHelpers) {
+	interval := config.Spec.Auth.LDAP.SyncInterval
+	if interval <= 0 {
+		interval = defaultLDAPSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := SyncLDAPUsers(ctx, dh); err != nil {
+			util.Logger(ctx).Error("Error syncing LDAP users", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func SyncLDAPUsers(ctx context.Context, dh *device.DeviceHelpers) error {
+	userStore := datastore.NewUserStore()
+	users, err := userStore.FindByQuery(ctx, "provider = ? AND disabled = ?", ldapProvider, false)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	emails := make([]string, 0, len(users))
+	for _, user := range users {
+		emails = append(emails, user.Email)
+	}
+
+	// users are only disabled after a successful lookup so
+	// that an unreachable directory doesn't disable everyone
+	entries, err := ldap.New().LookupEmails(emails)
+	if err != nil {
+		return errors.Wrap(err, "failed to look up users in ldap")
+	}
+
+	// the network policies match devices by
+	// their owner's groups
+	changed := false
+	for _, user := range users {
+		entry, ok := entries[user.Email]
+		if !ok {
+			util.Logger(ctx).Info("Disabling user that left the directory", zap.String("email", user.Email))
+			user.DisabledByDirectory = true
+			if err := DisableUser(ctx, dh, user); err != nil {
+				util.Logger(ctx).Error("Error disabling user", zap.String("email", user.Email), zap.Error(err))
+			}
+			changed = true
+			continue
+		}
+
+		if !sameGroups(user.Groups, entry.Groups) {
+			changed = true
+		}
+		user.Groups = entry.Groups
+		if entry.Name != "" {
+			user.Username = entry.Name
+		}
+		if group := config.Spec.Auth.LDAP.AdminGroup; group != "" {
+			user.Admin = user.InGroup(group)
+		}
+		if roles, ok := rbac.RolesForGroups(user.Groups); ok {
+			user.Roles = roles
+		}
+		if err := userStore.SaveUser(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	if changed && config.Spec.WG.Enabled {
+		if err := dh.ConfigureFirewall(ctx, nil); err != nil {
+			return errors.Wrap(err, "failed to configure firewall")
+		}
+	}
+	return nil
+}
+
+func sameGroups(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package users
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/helpers/device"
+	"github.com/waas-app/WaaS/infra/auth"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/proto/proto"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const minPasswordLength = 8
+
+// escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// UserSvc is a gRPC service for admins to manage users.
+type UserSvc struct {
+	DeviceHelpers *device.DeviceHelpers
+}
+
+func (u *UserSvc) ListUsers(ctx context.Context, req *proto.ListUsersReq) (*proto.ListUsersRes, error) {
+	query := "1 = 1"
+	values := []interface{}{}
+	if !req.GetIncludeDisabled() {
+		query += " AND disabled = ?"
+		values = append(values, false)
+	}
+	if search := strings.TrimSpace(req.GetQuery()); search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+		query += " AND (lower(email) LIKE ? OR lower(username) LIKE ?)"
+		values = append(values, pattern, pattern)
+	}
+
+	users, err := datastore.NewUserStore().FindByQuery(ctx, query, values...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list users")
+	}
+
+	res := &proto.ListUsersRes{}
+	for _, user := range users {
+		res.Items = append(res.Items, mapUser(user))
+	}
+	return res, nil
+}
+
+func (u *UserSvc) CreateUser(ctx context.Context, req *proto.CreateUserReq) (*proto.User, error) {
+	email := strings.TrimSpace(req.GetEmail())
+	if !strings.Contains(email, "@") {
+		return nil, status.Errorf(codes.InvalidArgument, "a valid email is required")
+	}
+	if req.GetInvite() {
+		if req.GetPassword() != "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invited users choose their own password")
+		}
+		if !auth.InvitesEnabled() {
+			return nil, status.Errorf(codes.FailedPrecondition, "invites need mail and local passwords")
+		}
+	} else if len(req.GetPassword()) < minPasswordLength {
+		return nil, status.Errorf(codes.InvalidArgument, "the password must have at least %d characters", minPasswordLength)
+	}
+	if err := validateRoles(req.GetRoles()); err != nil {
+		return nil, err
+	}
+
+	userStore := datastore.NewUserStore()
+	found, err := userStore.FindByQuery(ctx, "lower(email) = ?", strings.ToLower(email))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create user")
+	}
+	if len(found) > 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "a user with the email already exists")
+	}
+
+	user := &model.User{
+		Username: req.GetName(),
+		Email:    email,
+		Admin:    req.GetAdmin(),
+		Roles:    req.GetRoles(),
+	}
+	if user.Username == "" {
+		user.Username = email
+	}
+	if !req.GetInvite() {
+		password, err := util.HashPassword(req.GetPassword())
+		if err != nil {
+			grpc_zap.Extract(ctx).Error("failed to hash password", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to create user")
+		}
+		user.PutPassword(password)
+	}
+	if err := userStore.SaveUser(ctx, user); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create user")
+	}
+
+	if req.GetInvite() {
+		invitedBy, _ := ctx.Value(config.CurrentUser).(*model.User)
+		if err := auth.SendInvite(ctx, user, invitedBy); err != nil {
+			grpc_zap.Extract(ctx).Error("failed to invite user", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "the user was created but the invite failed")
+		}
+	}
+
+	return mapUser(user), nil
+}
+
+func (u *UserSvc) UpdateUserRoles(ctx context.Context, req *proto.UpdateUserRolesReq) (*proto.User, error) {
+	user, err := u.otherUser(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRoles(req.GetRoles()); err != nil {
+		return nil, err
+	}
+
+	user.Admin = req.GetAdmin()
+	user.Roles = req.GetRoles()
+	if err := datastore.NewUserStore().SaveUser(ctx, user); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update user")
+	}
+
+	return mapUser(user), nil
+}
+
+func (u *UserSvc) SetUserDisabled(ctx context.Context, req *proto.SetUserDisabledReq) (*proto.User, error) {
+	user, err := u.otherUser(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	// an admin's decision isn't undone by the user logging in
+	user.DisabledByDirectory = false
+	if req.GetDisabled() {
+		err = DisableUser(ctx, u.DeviceHelpers, user)
+	} else {
+		user.Disabled = false
+		err = datastore.NewUserStore().SaveUser(ctx, user)
+	}
+	if err != nil {
+		grpc_zap.Extract(ctx).Error("failed to update user", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to update user")
+	}
+
+	return mapUser(user), nil
+}
+
+func (u *UserSvc) DeleteUser(ctx context.Context, req *proto.DeleteUserReq) (*empty.Empty, error) {
+	user, err := u.otherUser(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := DeleteUser(ctx, u.DeviceHelpers, user); err != nil {
+		grpc_zap.Extract(ctx).Error("failed to delete user", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to delete user")
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// otherUser loads the user with the id, admins can't change
+// themselves so they don't lock themselves out by mistake.
+func (u *UserSvc) otherUser(ctx context.Context, id string) (*model.User, error) {
+	current, ok := ctx.Value(config.CurrentUser).(*model.User)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+	}
+	if id == current.Slug {
+		return nil, status.Errorf(codes.InvalidArgument, "you can't change your own user")
+	}
+
+	user, err := datastore.NewUserStore().FindUserBySlug(ctx, id)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "user not found")
+	}
+	return user, nil
+}
+
+func validateRoles(roles []string) error {
+	for _, role := range roles {
+		if !rbac.ValidRole(role) {
+			return status.Errorf(codes.InvalidArgument, "unknown role %s", role)
+		}
+	}
+	return nil
+}
+
+func mapUser(u *model.User) *proto.User {
+	return &proto.User{
+		Id:          u.Slug,
+		Name:        u.Username,
+		Email:       u.Email,
+		Admin:       u.Admin,
+		Roles:       u.Roles,
+		Groups:      u.Groups,
+		Provider:    u.Provider,
+		Disabled:    u.Disabled,
+		TotpEnabled: u.GetTOTPSecretKey() != "",
+	}
+}
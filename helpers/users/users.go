@@ -0,0 +1,80 @@
+package users
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/helpers/device"
+	"github.com/waas-app/WaaS/model"
+)
+
+// DisableUser disables the user and deletes their devices,
+// which removes them from WireGuard.
+func DisableUser(ctx context.Context, dh *device.DeviceHelpers, user *model.User) error {
+	user.Disabled = true
+	if err := datastore.NewUserStore().SaveUser(ctx, user); err != nil {
+		return err
+	}
+	return deleteDevices(ctx, dh, user)
+}
+
+// DeleteUser deletes the user along with their devices, address
+// reservations, peer rules and API tokens.
+func DeleteUser(ctx context.Context, dh *device.DeviceHelpers, user *model.User) error {
+	if err := deleteDevices(ctx, dh, user); err != nil {
+		return err
+	}
+
+	reservations, err := dh.ListReservations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if r.Owner != user.Slug {
+			continue
+		}
+		if err := dh.UnreserveAddresses(ctx, r.Owner, r.Device); err != nil {
+			return errors.Wrapf(err, "failed to remove the reservation of %s", r.Device)
+		}
+	}
+
+	rules, err := dh.ListPeerRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if !rule.Involves(user.Slug) {
+			continue
+		}
+		if err := dh.DeletePeerRule(ctx, rule); err != nil {
+			return errors.Wrapf(err, "failed to delete peer rule %d", rule.ID)
+		}
+	}
+
+	tokenStore := datastore.NewAPITokenStore()
+	tokens, err := tokenStore.ListForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := tokenStore.Delete(ctx, token); err != nil {
+			return err
+		}
+	}
+
+	return datastore.NewUserStore().DeleteUser(ctx, user)
+}
+
+func deleteDevices(ctx context.Context, dh *device.DeviceHelpers, user *model.User) error {
+	devices, err := dh.ListDevices(ctx, user.Slug)
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		if err := dh.DeleteDevice(ctx, user.Slug, d.Name); err != nil {
+			return errors.Wrapf(err, "failed to delete device %s", d.Name)
+		}
+	}
+	return nil
+}
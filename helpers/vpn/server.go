@@ -5,11 +5,15 @@ import (
 	"strings"
 
 	"github.com/golang/protobuf/ptypes/wrappers"
+	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"github.com/place1/wg-embed/pkg/wgembed"
 	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/rbac"
 	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/ipam"
 	"github.com/waas-app/WaaS/model"
 	"github.com/waas-app/WaaS/proto/proto"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -30,17 +34,46 @@ func (v *VPNServer) Info(ctx context.Context, req *proto.InfoReq) (*proto.InfoRe
 	}
 
 	return &proto.InfoRes{
-		Host:       stringValue(&config.Spec.ExternalHost),
-		PublicKey:  publicKey,
-		Port:       int32(config.Spec.WG.Port),
-		HostVpnIp:  ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP.String(),
-		IsAdmin:    user.Admin,
-		AllowedIps: allowedIPs(config.Spec.VPN.AllowedIPs),
-		DnsEnabled: config.Spec.DNS.Enabled,
-		DnsAddress: ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP.String(),
+		Host:        stringValue(&config.Spec.ExternalHost),
+		PublicKey:   publicKey,
+		Port:        int32(config.Spec.WG.Port),
+		HostVpnIp:   ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP.String(),
+		IsAdmin:     user.Admin,
+		AllowedIps:  allowedIPs(config.Spec.VPN.AllowedIPs),
+		DnsEnabled:  config.Spec.DNS.Enabled,
+		DnsAddress:  ip.GetWireGuardServerIP(config.Spec.VPN.CIDR).IP.String(),
+		Roles:       user.Roles,
+		Permissions: permissions(user),
 	}, nil
 }
 
+func (v *VPNServer) PoolUsage(ctx context.Context, req *proto.PoolUsageReq) (*proto.PoolUsageRes, error) {
+	res := &proto.PoolUsageRes{}
+	for _, pool := range ipam.ConfiguredPools() {
+		usage, err := pool.Usage(ctx)
+		if err != nil {
+			grpc_zap.Extract(ctx).Error("failed to get pool usage", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to get pool usage")
+		}
+
+		res.Pools = append(res.Pools, &proto.PoolUsage{
+			Cidr: usage.CIDR,
+			Size: usage.Size,
+			Used: usage.Used,
+		})
+	}
+
+	return res, nil
+}
+
+func permissions(user *model.User) []string {
+	permissions := []string{}
+	for _, p := range rbac.Permissions(user) {
+		permissions = append(permissions, string(p))
+	}
+	return permissions
+}
+
 func allowedIPs(allowedIPs []string) string {
 	return strings.Join(allowedIPs, ", ")
 }
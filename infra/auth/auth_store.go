@@ -24,10 +24,12 @@ func (a AuthStore) Load(ctx context.Context, key string) (authboss.User, error)
 	key = strings.TrimSpace(key)
 
 	//need to check key to see if email or phone
-	if strings.Contains(key, "@") {
-		if err := db.Where("lower(email) = ?", strings.ToLower(key)).First(&user).Error; err != nil {
-			return nil, authboss.ErrUserNotFound
-		}
+	if !strings.Contains(key, "@") {
+		return nil, authboss.ErrUserNotFound
+	}
+	// disabled users are logged out and can't log in
+	if err := db.Where("lower(email) = ? AND disabled = ?", strings.ToLower(key), false).First(&user).Error; err != nil {
+		return nil, authboss.ErrUserNotFound
 	}
 	return &user, nil
 }
@@ -68,6 +70,25 @@ func (a AuthStore) Create(ctx context.Context, user authboss.User) error {
 	return nil
 }
 
+// LoadByRecoverSelector finds the user with a password reset or invite token.
+func (a AuthStore) LoadByRecoverSelector(ctx context.Context, selector string) (authboss.RecoverableUser, error) {
+	db := database.Instance(ctx)
+	var user model.User
+	if err := db.Where("recover_selector = ? AND disabled = ?", selector, false).First(&user).Error; err != nil {
+		return nil, authboss.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (a AuthStore) LoadByConfirmSelector(ctx context.Context, selector string) (authboss.ConfirmableUser, error) {
+	db := database.Instance(ctx)
+	var user model.User
+	if err := db.Where("confirm_selector = ? AND disabled = ?", selector, false).First(&user).Error; err != nil {
+		return nil, authboss.ErrUserNotFound
+	}
+	return &user, nil
+}
+
 // CreatingServerStorer
 func (a AuthStore) New(ctx context.Context) authboss.User {
 	return &model.User{}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	util.InitLogger()
+	os.Exit(m.Run())
+}
+
+// fakeUserStore keeps users in memory, it understands
+// the queries of this package only.
+type fakeUserStore struct {
+	mu    sync.Mutex
+	users []*model.User
+}
+
+func newFakeUserStore(users ...*model.User) *fakeUserStore {
+	s := &fakeUserStore{}
+	for _, user := range users {
+		s.SaveUser(context.Background(), user)
+	}
+	return s
+}
+
+func (s *fakeUserStore) SaveUser(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *user
+	if saved.ID == 0 {
+		saved.ID = uint(len(s.users) + 1)
+		user.ID = saved.ID
+		s.users = append(s.users, &saved)
+		return nil
+	}
+	for i, u := range s.users {
+		if u.ID == saved.ID {
+			s.users[i] = &saved
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (s *fakeUserStore) FindByQuery(ctx context.Context, query string, values ...interface{}) ([]*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if query != "lower(email) = ?" {
+		return nil, fmt.Errorf("unsupported query %q", query)
+	}
+	found := []*model.User{}
+	for _, u := range s.users {
+		if strings.ToLower(u.Email) == values[0] {
+			user := *u
+			found = append(found, &user)
+		}
+	}
+	return found, nil
+}
+
+func (s *fakeUserStore) find(match func(*model.User) bool) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if match(u) {
+			user := *u
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *fakeUserStore) FindUserByID(ctx context.Context, id uint) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.ID == id })
+}
+
+func (s *fakeUserStore) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.Email == email })
+}
+
+func (s *fakeUserStore) FindUserBySlug(ctx context.Context, slug string) (*model.User, error) {
+	return s.find(func(u *model.User) bool { return u.Slug == slug })
+}
+
+func (s *fakeUserStore) DeleteUser(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, u := range s.users {
+		if u.ID == user.ID {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// all returns the stored users by id.
+func (s *fakeUserStore) all() []model.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []model.User{}
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	return users
+}
@@ -7,9 +7,13 @@ import (
 
 	"github.com/volatiletech/authboss/v3"
 	_ "github.com/volatiletech/authboss/v3/auth"
+	_ "github.com/volatiletech/authboss/v3/confirm"
 	"github.com/volatiletech/authboss/v3/defaults"
+	_ "github.com/volatiletech/authboss/v3/lock"
+	_ "github.com/volatiletech/authboss/v3/recover"
 	_ "github.com/volatiletech/authboss/v3/register"
 	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/mail"
 	"github.com/waas-app/WaaS/util"
 	"go.uber.org/zap"
 )
@@ -40,6 +44,14 @@ func InitializeAuthBoss() error {
 	ab.Config.Core.ViewRenderer = defaults.JSONRenderer{}
 	ab.Config.Core.Logger = NewLogger()
 	ab.Config.Modules.ExpireAfter = time.Duration(30 * 24 * time.Hour)
+	ab.Config.Modules.ResponseOnUnauthed = authboss.RespondUnauthorized
+	ab.Config.Modules.LockAfter = config.Spec.Auth.Lock.Attempts
+	if config.Spec.Auth.Lock.Window > 0 {
+		ab.Config.Modules.LockWindow = config.Spec.Auth.Lock.Window
+	}
+	if config.Spec.Auth.Lock.Duration > 0 {
+		ab.Config.Modules.LockDuration = config.Spec.Auth.Lock.Duration
+	}
 	emailRule := defaults.Rules{
 		FieldName: "email", Required: true,
 		MatchError: "Must be a valid e-mail address",
@@ -66,10 +78,53 @@ func InitializeAuthBoss() error {
 	}
 	defaults.SetCore(&ab.Config, true, false)
 
-	if err := ab.Init(); err != nil {
-		util.Logger(context.Background()).Fatal("Failed to initialize Auth boss", zap.Error(err))
+	if mail.Enabled() {
+		if err := setupMail(ab); err != nil {
+			util.Logger(context.Background()).Error("Failed to set up mail", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := ab.Init(modules()...); err != nil {
+		util.Logger(context.Background()).Error("Failed to initialize Auth boss", zap.Error(err))
+		return err
+	}
+
+	if err := setupTOTP(ab); err != nil {
+		util.Logger(context.Background()).Error("Failed to set up TOTP", zap.Error(err))
 		return err
 	}
 
 	return nil
 }
+
+// modules returns the authboss modules enabled by the config.
+func modules() []string {
+	modules := []string{"auth"}
+	if config.Spec.Auth.LDAP.URL != "" {
+		modules = []string{ModuleLDAP}
+	}
+	if config.Spec.Auth.Lock.Attempts > 0 {
+		modules = append(modules, "lock")
+	}
+	// LDAP users are created from the directory, signing
+	// up could claim the email of someone in it
+	registration := !config.Spec.Auth.DisableRegistration && config.Spec.Auth.LDAP.URL == ""
+	if registration {
+		modules = append(modules, "register")
+	}
+	if config.Spec.Auth.OIDC.Issuer != "" {
+		modules = append(modules, ModuleOIDC)
+	}
+	if mail.Enabled() {
+		// users who sign up confirm their email
+		if registration {
+			modules = append(modules, "confirm")
+		}
+		// LDAP passwords are changed in the directory
+		if config.Spec.Auth.LDAP.URL == "" {
+			modules = append(modules, "recover")
+		}
+	}
+	return modules
+}
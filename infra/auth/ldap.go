@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/auth"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/ldap"
+)
+
+// ModuleLDAP is the name of the authboss module for LDAP logins,
+// it replaces the auth module.
+const ModuleLDAP = "ldap"
+
+func init() {
+	authboss.RegisterModule(ModuleLDAP, &LDAP{})
+}
+
+// LDAP logs users in by binding to the directory with their
+// password. It serves the same routes as the auth module.
+type LDAP struct {
+	*authboss.Authboss
+
+	directory directory
+	users     datastore.UserStore
+}
+
+// directory is the part of ldap.Directory the module uses.
+type directory interface {
+	Authenticate(login string, password string) (*ldap.Entry, error)
+	Lookup(login string) (*ldap.Entry, error)
+}
+
+func (l *LDAP) Init(ab *authboss.Authboss) error {
+	l.Authboss = ab
+	l.directory = ldap.New()
+	l.users = datastore.NewUserStore()
+
+	if err := ab.Config.Core.ViewRenderer.Load(auth.PageLogin); err != nil {
+		return err
+	}
+
+	ab.Config.Core.Router.Get("/login", ab.Core.ErrorHandler.Wrap(l.LoginGet))
+	ab.Config.Core.Router.Post("/login", ab.Core.ErrorHandler.Wrap(l.LoginPost))
+	return nil
+}
+
+// email returns the email of the user with the login after a failed
+// login. Users are stored by email but the login is whatever the user
+// filter matches, e.g. the uid.
+func (l *LDAP) email(login string, loginErr error) (string, error) {
+	if loginErr != ldap.ErrInvalidCredentials {
+		return login, nil
+	}
+	entry, err := l.directory.Lookup(login)
+	switch {
+	case err == ldap.ErrNotFound:
+		return login, nil
+	case err != nil:
+		return "", err
+	case entry.Email == "":
+		return login, nil
+	}
+	return entry.Email, nil
+}
+
+func (l *LDAP) LoginGet(w http.ResponseWriter, r *http.Request) error {
+	data := authboss.HTMLData{}
+	if redir := r.URL.Query().Get(authboss.FormValueRedirect); len(redir) != 0 {
+		data[authboss.FormValueRedirect] = redir
+	}
+	return l.Core.Responder.Respond(w, r, http.StatusOK, auth.PageLogin, data)
+}
+
+// LoginPost checks the credentials against the directory, then
+// logs the user in the same way the auth module does.
+func (l *LDAP) LoginPost(w http.ResponseWriter, r *http.Request) error {
+	logger := l.RequestLogger(r)
+
+	validatable, err := l.Core.BodyReader.Read(auth.PageLogin, r)
+	if err != nil {
+		return err
+	}
+	creds := authboss.MustHaveUserValues(validatable)
+	login := creds.GetPID()
+
+	entry, err := l.directory.Authenticate(login, creds.GetPassword())
+	switch err {
+	case nil:
+	case ldap.ErrNotFound, ldap.ErrInvalidCredentials:
+		// failed logins of known users count towards locking them
+		email, err := l.email(login, err)
+		if err != nil {
+			return err
+		}
+		if user, err := l.Storage.Server.Load(r.Context(), email); err == nil {
+			r = r.WithContext(context.WithValue(r.Context(), authboss.CTXKeyUser, user))
+			handled, err := l.Events.FireAfter(authboss.EventAuthFail, w, r)
+			if err != nil {
+				return err
+			} else if handled {
+				return nil
+			}
+		} else if err != authboss.ErrUserNotFound {
+			return err
+		}
+
+		logger.Infof("user %s failed to log in with ldap", login)
+		data := authboss.HTMLData{authboss.DataErr: "Invalid Credentials"}
+		return l.Core.Responder.Respond(w, r, http.StatusOK, auth.PageLogin, data)
+	default:
+		return err
+	}
+
+	if entry.Email == "" {
+		return fmt.Errorf("ldap user %s has no email", login)
+	}
+
+	user, err := provisionUser(r.Context(), l.users, &identity{
+		Provider:   ModuleLDAP,
+		Email:      entry.Email,
+		Name:       entry.Name,
+		Groups:     entry.Groups,
+		AdminGroup: config.Spec.Auth.LDAP.AdminGroup,
+		Enable:     true,
+	})
+	switch err {
+	case nil:
+	case errUserDisabled, errAccountExists:
+		logger.Infof("user %s can't log in with ldap: %s", login, err)
+		data := authboss.HTMLData{authboss.DataErr: "Login failed"}
+		return l.Core.Responder.Respond(w, r, http.StatusOK, auth.PageLogin, data)
+	default:
+		return err
+	}
+	pid := user.GetPID()
+
+	r = r.WithContext(context.WithValue(r.Context(), authboss.CTXKeyUser, user))
+	r = r.WithContext(context.WithValue(r.Context(), authboss.CTXKeyValues, validatable))
+
+	handled, err := l.Events.FireBefore(authboss.EventAuth, w, r)
+	if err != nil {
+		return err
+	} else if handled {
+		return nil
+	}
+
+	handled, err = l.Events.FireBefore(authboss.EventAuthHijack, w, r)
+	if err != nil {
+		return err
+	} else if handled {
+		return nil
+	}
+
+	logger.Infof("user %s logged in with ldap", pid)
+	authboss.PutSession(w, authboss.SessionKey, pid)
+	authboss.DelSession(w, authboss.SessionHalfAuthKey)
+
+	handled, err = l.Events.FireAfter(authboss.EventAuth, w, r)
+	if err != nil {
+		return err
+	} else if handled {
+		return nil
+	}
+
+	ro := authboss.RedirectOptions{
+		Code:             http.StatusTemporaryRedirect,
+		RedirectPath:     l.Paths.AuthLoginOK,
+		FollowRedirParam: true,
+	}
+	return l.Core.Redirector.Redirect(w, r, ro)
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/defaults"
+	"github.com/waas-app/WaaS/infra/ldap"
+	"github.com/waas-app/WaaS/model"
+)
+
+// fakeDirectory has one user who logs in with their uid.
+type fakeDirectory struct{}
+
+var directoryEntry = &ldap.Entry{DN: "uid=jdoe,dc=example,dc=com", Email: "jdoe@example.com", Name: "J. Doe"}
+
+func (fakeDirectory) Lookup(login string) (*ldap.Entry, error) {
+	if login != "jdoe" {
+		return nil, ldap.ErrNotFound
+	}
+	return directoryEntry, nil
+}
+
+func (d fakeDirectory) Authenticate(login string, password string) (*ldap.Entry, error) {
+	entry, err := d.Lookup(login)
+	if err != nil {
+		return nil, err
+	}
+	if password != "secret" {
+		return nil, ldap.ErrInvalidCredentials
+	}
+	return entry, nil
+}
+
+func TestLDAPFailedLoginsLock(t *testing.T) {
+	users := newFakeUserStore(
+		&model.User{Email: "jdoe@example.com", Provider: ModuleLDAP},
+		&model.User{Email: "other@example.com", Provider: ModuleLDAP},
+	)
+
+	ab := authboss.New()
+	ab.Config.Storage.Server = fakeAuthStore{users}
+	ab.Config.Core.ViewRenderer = defaults.JSONRenderer{}
+	defaults.SetCore(&ab.Config, true, false)
+	ab.Config.Core.Logger = defaults.NewLogger(io.Discard)
+	ab.Config.Core.ErrorHandler = defaults.NewErrorHandler(ab.Config.Core.Logger)
+	ab.Config.Modules.LockAfter = 3
+	if err := ab.Init("lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &LDAP{}
+	if err := l.Init(ab); err != nil {
+		t.Fatal(err)
+	}
+	l.directory = fakeDirectory{}
+	l.users = users
+
+	login := func(login string) int {
+		body := `{"email":"` + login + `","password":"wrong"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		ab.Config.Core.Router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, name := range []string{"jdoe", "JDoe@example.com", "nobody"} {
+		if code := login(name); code != http.StatusOK {
+			t.Errorf("%s: got status %d", name, code)
+		}
+	}
+
+	// the uid and the email both count, logins of
+	// users that aren't in the directory don't
+	stored := users.all()
+	if stored[0].AttemptCount != 2 {
+		t.Errorf("expected two failed logins of jdoe, got %d", stored[0].AttemptCount)
+	}
+	if stored[1].AttemptCount != 0 {
+		t.Errorf("expected no failed logins of other, got %d", stored[1].AttemptCount)
+	}
+
+	login("jdoe")
+	if stored := users.all(); !stored[0].Locked.After(time.Now()) || stored[0].AttemptCount != 3 {
+		t.Errorf("expected jdoe to be locked, got %+v", stored[0])
+	}
+}
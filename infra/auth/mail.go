@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/volatiletech/authboss/v3"
+	abrecover "github.com/volatiletech/authboss/v3/recover"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/mail"
+	"github.com/waas-app/WaaS/model"
+)
+
+const (
+	// how long invite links are valid
+	inviteDuration = 7 * 24 * time.Hour
+
+	emailInviteHTML = "invite_html"
+	emailInviteTxt  = "invite_txt"
+)
+
+var ErrInvitesDisabled = errors.New("invites need mail and local passwords")
+
+// setupMail sends the emails of the recover and confirm
+// modules and invites over SMTP.
+func setupMail(ab *authboss.Authboss) error {
+	mailer, err := mail.NewMailer()
+	if err != nil {
+		return err
+	}
+
+	ab.Config.Core.Mailer = mailer
+	ab.Config.Core.MailRenderer = mail.NewRenderer()
+	ab.Config.Mail.From = config.Spec.Mail.From
+	ab.Config.Mail.FromName = config.Spec.Mail.FromName
+	ab.Config.Mail.SubjectPrefix = "[WaaS] "
+
+	// users of identity providers have no password to reset
+	ab.Events.Before(authboss.EventRecoverStart, func(w http.ResponseWriter, r *http.Request, handled bool) (bool, error) {
+		user, ok := r.Context().Value(authboss.CTXKeyUser).(*model.User)
+		if !ok || user.Provider == "" {
+			return false, nil
+		}
+		ab.RequestLogger(r).Infof("user %s of %s tried to reset their password", user.GetPID(), user.Provider)
+		return true, ab.Config.Core.Redirector.Redirect(w, r, authboss.RedirectOptions{
+			Code:         http.StatusTemporaryRedirect,
+			RedirectPath: ab.Config.Paths.RecoverOK,
+			Success:      "An email has been sent to you with further instructions on how to reset your password.",
+		})
+	})
+
+	return ab.Config.Core.MailRenderer.Load(emailInviteHTML, emailInviteTxt)
+}
+
+// InvitesEnabled reports whether users can be invited
+// to choose their password.
+func InvitesEnabled() bool {
+	return mail.Enabled() && config.Spec.Auth.LDAP.URL == ""
+}
+
+// SendInvite emails the user a link to choose their password,
+// the link is a password reset link that's valid for longer.
+func SendInvite(ctx context.Context, user *model.User, invitedBy *model.User) error {
+	if !InvitesEnabled() {
+		return ErrInvitesDisabled
+	}
+
+	selector, verifier, token, err := abrecover.GenerateRecoverCreds()
+	if err != nil {
+		return err
+	}
+	user.PutRecoverSelector(selector)
+	user.PutRecoverVerifier(verifier)
+	user.PutRecoverExpiry(time.Now().UTC().Add(inviteDuration))
+	if err := ab.Config.Storage.Server.Save(ctx, user); err != nil {
+		return err
+	}
+
+	query := url.Values{abrecover.FormValueToken: []string{token}}
+	inviteURL := fmt.Sprintf("%s%s?%s", ab.Config.Paths.RootURL, path.Join(ab.Config.Paths.Mount, "recover/end"), query.Encode())
+
+	email := authboss.Email{
+		To:       []string{user.GetEmail()},
+		From:     ab.Config.Mail.From,
+		FromName: ab.Config.Mail.FromName,
+		Subject:  ab.Config.Mail.SubjectPrefix + "You're invited",
+	}
+	ro := authboss.EmailResponseOptions{
+		HTMLTemplate: emailInviteHTML,
+		TextTemplate: emailInviteTxt,
+		Data: authboss.HTMLData{
+			"invite_url": inviteURL,
+			"invited_by": invitedBy.Username,
+			"valid_days": int(inviteDuration / (24 * time.Hour)),
+		},
+	}
+	return errors.Wrap(ab.Email(ctx, email, ro), "failed to send the invite")
+}
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/defaults"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+// sentMail is an email received by the SMTP stand-in.
+type sentMail struct {
+	From    string
+	To      []string
+	Subject string
+	// Parts are the bodies by content type
+	Parts map[string]string
+}
+
+// smtpServer is an SMTP stand-in that accepts every email,
+// it returns its address and the emails it receives.
+func smtpServer(t *testing.T) (string, <-chan sentMail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan sentMail, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(t, textproto.NewConn(conn), mails)
+		}
+	}()
+	return listener.Addr().String(), mails
+}
+
+func serveSMTP(t *testing.T, conn *textproto.Conn, mails chan<- sentMail) {
+	defer conn.Close()
+	conn.PrintfLine("220 localhost ESMTP")
+
+	sent := sentMail{}
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			conn.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			sent.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			conn.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			sent.To = append(sent.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			conn.PrintfLine("250 OK")
+		case cmd == "DATA":
+			conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			if err := parseMail(data, &sent); err != nil {
+				t.Errorf("failed to parse the email: %s", err)
+			}
+			mails <- sent
+			sent = sentMail{}
+			conn.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			conn.PrintfLine("221 Bye")
+			return
+		default:
+			conn.PrintfLine("250 OK")
+		}
+	}
+}
+
+func parseMail(data []byte, sent *sentMail) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	sent.Subject = msg.Header.Get("Subject")
+	sent.Parts = map[string]string{}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		sent.Parts[mediaType] = strings.TrimSpace(strings.ReplaceAll(string(body), "\r\n", "\n"))
+	}
+}
+
+func receive(t *testing.T, mails <-chan sentMail) sentMail {
+	t.Helper()
+	select {
+	case sent := <-mails:
+		return sent
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+		return sentMail{}
+	}
+}
+
+// fakeAuthStore is the authboss storer of a fakeUserStore.
+type fakeAuthStore struct {
+	*fakeUserStore
+}
+
+func (s fakeAuthStore) Load(ctx context.Context, key string) (authboss.User, error) {
+	found, err := s.FindByQuery(ctx, "lower(email) = ?", strings.ToLower(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, authboss.ErrUserNotFound
+	}
+	return found[0], nil
+}
+
+func (s fakeAuthStore) Save(ctx context.Context, user authboss.User) error {
+	return s.SaveUser(ctx, user.(*model.User))
+}
+
+func (s fakeAuthStore) loadBy(match func(*model.User) bool) (authboss.User, error) {
+	user, err := s.find(match)
+	if err != nil {
+		return nil, authboss.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s fakeAuthStore) LoadByRecoverSelector(ctx context.Context, selector string) (authboss.RecoverableUser, error) {
+	user, err := s.loadBy(func(u *model.User) bool { return u.RecoverSelector == selector })
+	if err != nil {
+		return nil, err
+	}
+	return user.(*model.User), nil
+}
+
+func (s fakeAuthStore) LoadByConfirmSelector(ctx context.Context, selector string) (authboss.ConfirmableUser, error) {
+	user, err := s.loadBy(func(u *model.User) bool { return u.ConfirmSelector == selector })
+	if err != nil {
+		return nil, err
+	}
+	return user.(*model.User), nil
+}
+
+// mailAuthboss replaces the package's authboss with one that sends
+// emails to an SMTP stand-in, the way InitializeAuthBoss sets it up.
+func mailAuthboss(t *testing.T, templates string, users ...*model.User) (*fakeUserStore, <-chan sentMail) {
+	addr, mails := smtpServer(t)
+
+	spec := config.Spec
+	previous := ab
+	t.Cleanup(func() {
+		config.Spec = spec
+		ab = previous
+	})
+	config.Spec.Mail.SMTP = addr
+	config.Spec.Mail.From = "waas@example.com"
+	config.Spec.Mail.FromName = "WaaS"
+	config.Spec.Mail.Templates = templates
+
+	store := newFakeUserStore(users...)
+	ab = authboss.New()
+	ab.Config.Paths.RootURL = "https://waas.example.com"
+	ab.Config.Paths.Mount = "/auth"
+	ab.Config.Storage.Server = fakeAuthStore{store}
+	ab.Config.Core.ViewRenderer = defaults.JSONRenderer{}
+	defaults.SetCore(&ab.Config, true, false)
+	ab.Config.Core.Logger = defaults.NewLogger(io.Discard)
+	ab.Config.Core.ErrorHandler = defaults.NewErrorHandler(ab.Config.Core.Logger)
+	ab.Config.Modules.MailNoGoroutine = true
+
+	if err := setupMail(ab); err != nil {
+		t.Fatal(err)
+	}
+	if err := ab.Init("recover", "confirm"); err != nil {
+		t.Fatal(err)
+	}
+	return store, mails
+}
+
+// startRecover asks for a password reset link.
+func startRecover(email string) {
+	req := httptest.NewRequest(http.MethodPost, "/recover", strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	ab.Config.Core.Router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestRecoverMail(t *testing.T) {
+	_, mails := mailAuthboss(t, "",
+		&model.User{Email: "local@example.com", EncryptedPassword: "hash"},
+		&model.User{Email: "ldap@example.com", Provider: ModuleLDAP},
+	)
+
+	startRecover("local@example.com")
+	sent := receive(t, mails)
+	if sent.From != "waas@example.com" || len(sent.To) != 1 || sent.To[0] != "local@example.com" {
+		t.Errorf("unexpected envelope from %s to %v", sent.From, sent.To)
+	}
+	if sent.Subject != "[WaaS] Password Reset" {
+		t.Errorf("unexpected subject %q", sent.Subject)
+	}
+	for _, part := range []string{"text/plain", "text/html"} {
+		if !strings.Contains(sent.Parts[part], "https://waas.example.com/auth/recover/end?token=") {
+			t.Errorf("expected the %s part to have the reset link, got %q", part, sent.Parts[part])
+		}
+	}
+
+	// users of identity providers have no password to reset
+	startRecover("ldap@example.com")
+	select {
+	case sent := <-mails:
+		t.Errorf("expected no email for an ldap user, got %+v", sent)
+	default:
+	}
+}
+
+func TestConfirmMail(t *testing.T) {
+	user := &model.User{Email: "new@example.com", EncryptedPassword: "hash", Confirmed: true}
+	store, mails := mailAuthboss(t, "", user)
+
+	// the register module fires this after signing up
+	req := httptest.NewRequest(http.MethodPost, "/register", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), authboss.CTXKeyUser, user))
+	if _, err := ab.Events.FireAfter(authboss.EventRegister, httptest.NewRecorder(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := receive(t, mails)
+	if len(sent.To) != 1 || sent.To[0] != "new@example.com" {
+		t.Errorf("unexpected recipients %v", sent.To)
+	}
+	if !strings.HasPrefix(sent.Subject, "[WaaS] ") {
+		t.Errorf("unexpected subject %q", sent.Subject)
+	}
+	if !strings.Contains(sent.Parts["text/plain"], "https://waas.example.com/auth/confirm?cnf=") {
+		t.Errorf("expected the confirm link, got %q", sent.Parts["text/plain"])
+	}
+	if stored := store.all(); stored[0].Confirmed || stored[0].ConfirmSelector == "" {
+		t.Errorf("expected the user to wait for confirmation, got %+v", stored[0])
+	}
+}
+
+func TestInviteMail(t *testing.T) {
+	user := &model.User{Email: "invited@example.com"}
+	store, mails := mailAuthboss(t, "", user)
+
+	if err := SendInvite(context.Background(), user, &model.User{Username: "Admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := receive(t, mails)
+	if len(sent.To) != 1 || sent.To[0] != "invited@example.com" {
+		t.Errorf("unexpected recipients %v", sent.To)
+	}
+	if sent.Subject != "[WaaS] You're invited" {
+		t.Errorf("unexpected subject %q", sent.Subject)
+	}
+	text := sent.Parts["text/plain"]
+	if !strings.HasPrefix(text, "Admin invited you to WaaS.") ||
+		!strings.Contains(text, "valid for 7 days") ||
+		!strings.Contains(text, "https://waas.example.com/auth/recover/end?token=") {
+		t.Errorf("unexpected invite %q", text)
+	}
+	if stored := store.all(); stored[0].RecoverSelector == "" || stored[0].RecoverTokenExpiry.Before(time.Now().Add(6*24*time.Hour)) {
+		t.Errorf("expected the invite token to be saved, got %+v", stored[0])
+	}
+}
+
+func TestMailTemplateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"invite.txt":  "Join {{.invited_by}}: {{.invite_url}}",
+		"recover.txt": "Reset: {{.recover_url}}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := &model.User{Email: "invited@example.com"}
+	_, mails := mailAuthboss(t, dir, user, &model.User{Email: "local@example.com", EncryptedPassword: "hash"})
+	if err := SendInvite(context.Background(), user, &model.User{Username: "Admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := receive(t, mails)
+	if text := sent.Parts["text/plain"]; !strings.HasPrefix(text, "Join Admin: https://waas.example.com/auth/recover/end?token=") {
+		t.Errorf("expected the overridden text template, got %q", text)
+	}
+	// templates that aren't overridden are the built in ones
+	if html := sent.Parts["text/html"]; !strings.HasPrefix(html, "<p>Admin invited you to WaaS.</p>") {
+		t.Errorf("expected the built in html template, got %q", html)
+	}
+
+	startRecover("local@example.com")
+	sent = receive(t, mails)
+	if text := sent.Parts["text/plain"]; !strings.HasPrefix(text, "Reset: https://waas.example.com/auth/recover/end?token=") {
+		t.Errorf("expected the overridden text template, got %q", text)
+	}
+}
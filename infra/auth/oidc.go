@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+	"github.com/volatiletech/authboss/v3"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// ModuleOIDC is the name of the authboss module for OIDC logins
+	ModuleOIDC = "oidc"
+
+	sessionOIDCState    = "oidc_state"
+	sessionOIDCNonce    = "oidc_nonce"
+	sessionOIDCVerifier = "oidc_verifier"
+)
+
+func init() {
+	authboss.RegisterModule(ModuleOIDC, &OIDC{})
+}
+
+// OIDC logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE. Users are created on their
+// first login.
+type OIDC struct {
+	*authboss.Authboss
+
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	users    datastore.UserStore
+}
+
+// oidcClaims are the claims of the id token mapped to a user.
+type oidcClaims struct {
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"-"`
+	Name          string   `json:"name"`
+	Nonce         string   `json:"nonce"`
+	Groups        []string `json:"-"`
+}
+
+func (o *OIDC) Init(ab *authboss.Authboss) error {
+	o.Authboss = ab
+	o.users = datastore.NewUserStore()
+
+	cfg := config.Spec.Auth.OIDC
+	provider, err := oidc.NewProvider(context.Background(), cfg.Issuer)
+	if err != nil {
+		return errors.Wrap(err, "failed to discover the oidc provider")
+	}
+
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(ab.Config.Paths.RootURL, "/") + ab.Config.Paths.Mount + "/oidc/callback"
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email"}
+	}
+
+	o.oauth2 = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+	}
+	o.verifier = provider.Verifier(&oidc.Config{ClientID: cfg.ClientID})
+
+	ab.Config.Core.Router.Get("/oidc/login", ab.Config.Core.ErrorHandler.Wrap(o.Start))
+	ab.Config.Core.Router.Get("/oidc/callback", ab.Config.Core.ErrorHandler.Wrap(o.End))
+	return nil
+}
+
+// Start redirects to the provider's login page.
+func (o *OIDC) Start(w http.ResponseWriter, r *http.Request) error {
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	authboss.PutSession(w, sessionOIDCState, state)
+	authboss.PutSession(w, sessionOIDCNonce, nonce)
+	authboss.PutSession(w, sessionOIDCVerifier, verifier)
+
+	challenge := sha256.Sum256([]byte(verifier))
+	url := o.oauth2.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+
+	ro := authboss.RedirectOptions{
+		Code:             http.StatusTemporaryRedirect,
+		RedirectPath:     url,
+		FollowRedirParam: false,
+	}
+	return o.Authboss.Config.Core.Redirector.Redirect(w, r, ro)
+}
+
+// End handles the provider's redirect back to us and logs the user in.
+func (o *OIDC) End(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	state, _ := authboss.GetSession(r, sessionOIDCState)
+	nonce, _ := authboss.GetSession(r, sessionOIDCNonce)
+	verifier, _ := authboss.GetSession(r, sessionOIDCVerifier)
+	authboss.DelSession(w, sessionOIDCState)
+	authboss.DelSession(w, sessionOIDCNonce)
+	authboss.DelSession(w, sessionOIDCVerifier)
+
+	if state == "" || r.FormValue("state") != state {
+		return errors.New("oidc state mismatch")
+	}
+
+	if reason := r.FormValue("error"); reason != "" {
+		util.Logger(ctx).Warn("OIDC login failed", zap.String("error", reason), zap.String("description", r.FormValue("error_description")))
+		return o.loginFailed(w, r, "Login failed")
+	}
+
+	token, err := o.oauth2.Exchange(ctx, r.FormValue("code"), oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return errors.Wrap(err, "failed to exchange the oidc code")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return errors.New("oidc token response has no id_token")
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify the oidc id_token")
+	}
+
+	claims, err := parseOIDCClaims(idToken)
+	if err != nil {
+		return err
+	}
+	if claims.Nonce != nonce {
+		return errors.New("oidc nonce mismatch")
+	}
+	if claims.Email == "" {
+		return errors.New("oidc id_token has no email claim")
+	}
+	// the email links the login to existing users,
+	// so it must be one the provider has checked
+	if !claims.EmailVerified {
+		util.Logger(ctx).Warn("OIDC login with unverified email", zap.String("email", claims.Email))
+		return o.loginFailed(w, r, "Your email address isn't verified")
+	}
+
+	user, err := provisionUser(ctx, o.users, &identity{
+		Provider:   ModuleOIDC,
+		Email:      claims.Email,
+		Name:       claims.Name,
+		Groups:     claims.Groups,
+		AdminGroup: config.Spec.Auth.OIDC.AdminGroup,
+	})
+	switch err {
+	case nil:
+	case errUserDisabled:
+		util.Logger(ctx).Warn("OIDC login of disabled user", zap.String("email", claims.Email))
+		return o.loginFailed(w, r, "Login failed")
+	case errAccountExists:
+		util.Logger(ctx).Warn("OIDC login with the email of another account", zap.String("email", claims.Email))
+		return o.loginFailed(w, r, "An account with this email already exists")
+	default:
+		return err
+	}
+
+	// users with 2FA enter their code before they're logged in
+	r = r.WithContext(context.WithValue(ctx, authboss.CTXKeyUser, user))
+	handled, err := o.Events.FireBefore(authboss.EventAuthHijack, w, r)
+	if err != nil {
+		return err
+	} else if handled {
+		return nil
+	}
+
+	authboss.PutSession(w, authboss.SessionKey, user.GetPID())
+	authboss.DelSession(w, authboss.SessionHalfAuthKey)
+
+	util.Logger(ctx).Info("OIDC login", zap.String("email", user.Email))
+	return o.Authboss.Config.Core.Redirector.Redirect(w, r, authboss.RedirectOptions{
+		Code:         http.StatusTemporaryRedirect,
+		RedirectPath: o.Authboss.Config.Paths.OAuth2LoginOK,
+		Success:      "Logged in successfully",
+	})
+}
+
+// loginFailed redirects to the login page with the failure.
+func (o *OIDC) loginFailed(w http.ResponseWriter, r *http.Request, failure string) error {
+	return o.Authboss.Config.Core.Redirector.Redirect(w, r, authboss.RedirectOptions{
+		Code:         http.StatusTemporaryRedirect,
+		RedirectPath: o.Authboss.Config.Paths.OAuth2LoginNotOK,
+		Failure:      failure,
+	})
+}
+
+func parseOIDCClaims(idToken *oidc.IDToken) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	if err := idToken.Claims(claims); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the oidc claims")
+	}
+
+	groupsClaim := config.Spec.Auth.OIDC.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	raw := map[string]interface{}{}
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the oidc claims")
+	}
+
+	// some providers send the flag as a string
+	switch verified := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+
+	// providers send either a list or a single group
+	switch groups := raw[groupsClaim].(type) {
+	case []interface{}:
+		claims.Groups = []string{}
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				claims.Groups = append(claims.Groups, name)
+			}
+		}
+	case string:
+		claims.Groups = []string{groups}
+	}
+	return claims, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
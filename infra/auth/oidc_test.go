@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/defaults"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+const oidcClientID = "waas"
+
+var oidcKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// mockProvider is an OIDC provider that logs in whoever asks
+// with the claims of the test. It only hands out tokens for the
+// code verifier that matches the challenge of the login.
+type mockProvider struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	claims map[string]interface{}
+	logins map[string]url.Values
+	// exchanges counts the codes exchanged with a valid verifier
+	exchanges int
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	p := &mockProvider{logins: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(oidcKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(oidcKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		code, _ := randomString()
+		p.mu.Lock()
+		p.logins[code] = query
+		p.mu.Unlock()
+
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		login, ok := p.logins[r.FormValue("code")]
+		delete(p.logins, r.FormValue("code"))
+		challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || login.Get("code_challenge_method") != "S256" ||
+			login.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		p.exchanges++
+
+		claims := map[string]interface{}{
+			"iss":   p.URL,
+			"aud":   oidcClientID,
+			"sub":   "subject",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": login.Get("nonce"),
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     signToken(t, claims),
+		})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *mockProvider) login(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+func signToken(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, oidcKey, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// oidcApp serves the OIDC module with the users of the store,
+// /ok and /notok show the outcome of a login.
+func oidcApp(t *testing.T, provider *mockProvider, users *fakeUserStore) *httptest.Server {
+	spec := config.Spec
+	t.Cleanup(func() { config.Spec = spec })
+	config.Spec.Auth.OIDC.Issuer = provider.URL
+	config.Spec.Auth.OIDC.ClientID = oidcClientID
+	config.Spec.Auth.OIDC.ClientSecret = "secret"
+	config.Spec.Auth.OIDC.AdminGroup = "admins"
+
+	mux := http.NewServeMux()
+	app := httptest.NewServer(mux)
+	t.Cleanup(app.Close)
+
+	ab := authboss.New()
+	ab.Config.Paths.RootURL = app.URL
+	ab.Config.Paths.Mount = "/auth"
+	ab.Config.Paths.OAuth2LoginOK = "/ok"
+	ab.Config.Paths.OAuth2LoginNotOK = "/notok"
+	key := []byte(strings.Repeat("k", 32))
+	ab.Config.Storage.SessionState = NewSessionStorer("session", key)
+	ab.Config.Storage.CookieState = NewCookieStorer(key, nil)
+	defaults.SetCore(&ab.Config, false, false)
+	ab.Config.Core.Logger = defaults.NewLogger(io.Discard)
+	ab.Config.Core.ErrorHandler = defaults.NewErrorHandler(ab.Config.Core.Logger)
+
+	o := &OIDC{}
+	if err := o.Init(ab); err != nil {
+		t.Fatal(err)
+	}
+	o.users = users
+
+	mux.Handle("/auth/", http.StripPrefix("/auth", ab.LoadClientStateMiddleware(ab.Config.Core.Router)))
+	mux.Handle("/ok", ab.LoadClientStateMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pid, _ := authboss.GetSession(r, authboss.SessionKey)
+		io.WriteString(w, "ok "+pid)
+	})))
+	mux.Handle("/notok", ab.LoadClientStateMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "notok "+authboss.FlashError(w, r))
+	})))
+	return app
+}
+
+// oidcLogin goes through the login and returns the page it ends on.
+func oidcLogin(t *testing.T, app *httptest.Server) string {
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	resp, err := client.Get(app.URL + "/auth/oidc/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	for name, test := range map[string]struct {
+		existing []*model.User
+		claims   map[string]interface{}
+		want     string
+		// the user with the email afterwards, nil when there's none
+		user *model.User
+	}{
+		"creates users": {
+			claims: map[string]interface{}{"email": "new@example.com", "email_verified": true, "name": "New", "groups": []string{"staff"}},
+			want:   "ok new@example.com",
+			user:   &model.User{ID: 1, Email: "new@example.com", Username: "New", Groups: []string{"staff"}, Provider: ModuleOIDC},
+		},
+		"admin group makes admins": {
+			claims: map[string]interface{}{"email": "admin@example.com", "email_verified": true, "groups": []string{"staff", "Admins"}},
+			want:   "ok admin@example.com",
+			user:   &model.User{ID: 1, Email: "admin@example.com", Groups: []string{"staff", "Admins"}, Admin: true, Provider: ModuleOIDC},
+		},
+		"leaving the admin group revokes admin": {
+			existing: []*model.User{{Email: "admin@example.com", Admin: true, Groups: []string{"admins"}, Provider: ModuleOIDC}},
+			claims:   map[string]interface{}{"email": "admin@example.com", "email_verified": true, "groups": "staff"},
+			want:     "ok admin@example.com",
+			user:     &model.User{ID: 1, Email: "admin@example.com", Groups: []string{"staff"}, Provider: ModuleOIDC},
+		},
+		"links users without a password": {
+			existing: []*model.User{{Email: "Invited@example.com", Username: "Invited"}},
+			claims:   map[string]interface{}{"email": "invited@example.com", "email_verified": "true"},
+			want:     "ok Invited@example.com",
+			user:     &model.User{ID: 1, Email: "Invited@example.com", Username: "Invited", Provider: ModuleOIDC},
+		},
+		"refuses users with a password": {
+			existing: []*model.User{{Email: "local@example.com", EncryptedPassword: "hash"}},
+			claims:   map[string]interface{}{"email": "local@example.com", "email_verified": true, "groups": []string{"admins"}},
+			want:     "notok An account with this email already exists",
+			user:     &model.User{ID: 1, Email: "local@example.com", EncryptedPassword: "hash"},
+		},
+		"refuses users of other providers": {
+			existing: []*model.User{{Email: "ldap@example.com", Provider: ModuleLDAP}},
+			claims:   map[string]interface{}{"email": "ldap@example.com", "email_verified": true},
+			want:     "notok An account with this email already exists",
+			user:     &model.User{ID: 1, Email: "ldap@example.com", Provider: ModuleLDAP},
+		},
+		"refuses unverified emails": {
+			existing: []*model.User{{Email: "unverified@example.com"}},
+			claims:   map[string]interface{}{"email": "unverified@example.com", "email_verified": false},
+			want:     "notok Your email address isn't verified",
+			user:     &model.User{ID: 1, Email: "unverified@example.com"},
+		},
+		"refuses emails that aren't known to be verified": {
+			claims: map[string]interface{}{"email": "unknown@example.com"},
+			want:   "notok Your email address isn't verified",
+		},
+		"refuses disabled users": {
+			existing: []*model.User{{Email: "disabled@example.com", Provider: ModuleOIDC, Disabled: true}},
+			claims:   map[string]interface{}{"email": "disabled@example.com", "email_verified": true},
+			want:     "notok Login failed",
+			user:     &model.User{ID: 1, Email: "disabled@example.com", Provider: ModuleOIDC, Disabled: true},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			provider := newMockProvider(t)
+			users := newFakeUserStore(test.existing...)
+			app := oidcApp(t, provider, users)
+
+			provider.login(test.claims)
+			if got := oidcLogin(t, app); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if provider.exchanges != 1 {
+				t.Errorf("expected one code exchange with a valid verifier, got %d", provider.exchanges)
+			}
+
+			stored := users.all()
+			switch {
+			case test.user == nil && len(stored) != 0:
+				t.Errorf("expected no users, got %+v", stored)
+			case test.user != nil && (len(stored) != 1 || !sameUser(stored[0], *test.user)):
+				t.Errorf("got users %+v, want %+v", stored, *test.user)
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsWrongVerifier(t *testing.T) {
+	provider := newMockProvider(t)
+	users := newFakeUserStore()
+	app := oidcApp(t, provider, users)
+	provider.login(map[string]interface{}{"email": "new@example.com", "email_verified": true})
+
+	// a callback with a code the session's verifier doesn't belong to
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, CheckRedirect: func(r *http.Request, via []*http.Request) error {
+		if strings.HasSuffix(r.URL.Path, "/oidc/callback") {
+			query := r.URL.Query()
+			stolen, _ := randomString()
+			provider.mu.Lock()
+			provider.logins[stolen] = url.Values{"code_challenge": {"other"}, "code_challenge_method": {"S256"}}
+			provider.mu.Unlock()
+			query.Set("code", stolen)
+			r.URL.RawQuery = query.Encode()
+		}
+		return nil
+	}}
+	resp, err := client.Get(app.URL + "/auth/oidc/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if strings.HasPrefix(string(body), "ok") || provider.exchanges != 0 {
+		t.Errorf("expected the exchange to fail, got %q", body)
+	}
+	if stored := users.all(); len(stored) != 0 {
+		t.Errorf("expected no users, got %+v", stored)
+	}
+}
+
+func sameUser(a, b model.User) bool {
+	return a.ID == b.ID && a.Email == b.Email && a.Username == b.Username &&
+		a.Admin == b.Admin && a.Provider == b.Provider && a.Disabled == b.Disabled &&
+		a.EncryptedPassword == b.EncryptedPassword &&
+		strings.Join(a.Groups, ",") == strings.Join(b.Groups, ",")
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/datastore"
+	"github.com/waas-app/WaaS/infra/rbac"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+var (
+	errUserDisabled = errors.New("user is disabled")
+	// errAccountExists is returned for identities whose email
+	// belongs to an account of someone else, e.g. one that
+	// signed up with a password or another provider
+	errAccountExists = errors.New("an account with this email already exists")
+)
+
+// identity is a user as described by an external identity provider.
+type identity struct {
+	Provider string
+	Email    string
+	Name     string
+	// Groups replace the user's groups unless nil
+	Groups []string
+	// AdminGroup makes the user an admin when they're in
+	// the group, admins aren't managed when it's empty
+	AdminGroup string
+	// Enable re-enables users the provider disabled
+	// itself, see model.User.DisabledByDirectory
+	Enable bool
+}
+
+// provisionUser loads or creates the user of an identity and
+// updates it from the identity. Existing users are only linked
+// when nobody could have claimed the email before, that is they
+// have no password and no other provider.
+func provisionUser(ctx context.Context, userStore datastore.UserStore, id *identity) (*model.User, error) {
+	// disabled users are included so that they aren't created again
+	found, err := userStore.FindByQuery(ctx, "lower(email) = ?", strings.ToLower(id.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{Email: id.Email}
+	if len(found) > 0 {
+		user = found[0]
+	}
+	if user.EncryptedPassword != "" || (user.Provider != "" && user.Provider != id.Provider) {
+		return nil, errAccountExists
+	}
+
+	if user.Disabled {
+		if !id.Enable || !user.DisabledByDirectory {
+			return nil, errUserDisabled
+		}
+		user.Disabled = false
+		user.DisabledByDirectory = false
+	}
+
+	user.Provider = id.Provider
+	if id.Name != "" {
+		user.Username = id.Name
+	}
+	if id.Groups != nil {
+		user.Groups = id.Groups
+	}
+	if id.AdminGroup != "" {
+		user.Admin = user.InGroup(id.AdminGroup)
+	}
+	if roles, ok := rbac.RolesForGroups(user.Groups); ok {
+		user.Roles = roles
+	}
+
+	if user.ID == 0 {
+		util.Logger(ctx).Info("Creating user", zap.String("email", user.Email), zap.String("provider", id.Provider))
+	}
+	if err := userStore.SaveUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+func TestProvisionUser(t *testing.T) {
+	for name, test := range map[string]struct {
+		existing *model.User
+		id       identity
+		err      error
+		user     model.User
+	}{
+		"enables users the directory disabled": {
+			existing: &model.User{Email: "left@example.com", Provider: ModuleLDAP, Disabled: true, DisabledByDirectory: true},
+			id:       identity{Provider: ModuleLDAP, Email: "left@example.com", Enable: true},
+			user:     model.User{ID: 1, Email: "left@example.com", Provider: ModuleLDAP},
+		},
+		"keeps users an admin disabled": {
+			existing: &model.User{Email: "banned@example.com", Provider: ModuleLDAP, Disabled: true},
+			id:       identity{Provider: ModuleLDAP, Email: "banned@example.com", Enable: true},
+			err:      errUserDisabled,
+			user:     model.User{ID: 1, Email: "banned@example.com", Provider: ModuleLDAP, Disabled: true},
+		},
+		"only enables users for providers that disable them": {
+			existing: &model.User{Email: "left@example.com", Provider: ModuleOIDC, Disabled: true, DisabledByDirectory: true},
+			id:       identity{Provider: ModuleOIDC, Email: "left@example.com"},
+			err:      errUserDisabled,
+			user:     model.User{ID: 1, Email: "left@example.com", Provider: ModuleOIDC, Disabled: true, DisabledByDirectory: true},
+		},
+		"refuses users with a password": {
+			existing: &model.User{Email: "local@example.com", EncryptedPassword: "hash"},
+			id:       identity{Provider: ModuleLDAP, Email: "local@example.com", Enable: true},
+			err:      errAccountExists,
+			user:     model.User{ID: 1, Email: "local@example.com", EncryptedPassword: "hash"},
+		},
+		"refuses users of other providers": {
+			existing: &model.User{Email: "oidc@example.com", Provider: ModuleOIDC},
+			id:       identity{Provider: ModuleLDAP, Email: "oidc@example.com", Enable: true},
+			err:      errAccountExists,
+			user:     model.User{ID: 1, Email: "oidc@example.com", Provider: ModuleOIDC},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			users := newFakeUserStore(test.existing)
+			if _, err := provisionUser(context.Background(), users, &test.id); err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if stored := users.all(); !reflect.DeepEqual(stored, []model.User{test.user}) {
+				t.Errorf("got users %+v, want %+v", stored, test.user)
+			}
+		})
+	}
+}
+
+func TestModulesWithLDAP(t *testing.T) {
+	spec := config.Spec
+	defer func() { config.Spec = spec }()
+	config.Spec.Auth.LDAP.URL = "ldaps://ldap.example.com"
+	config.Spec.Auth.DisableRegistration = false
+
+	want := []string{ModuleLDAP}
+	if got := modules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got modules %v, want %v", got, want)
+	}
+}
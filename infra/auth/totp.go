@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/otp/twofactor"
+	"github.com/volatiletech/authboss/v3/otp/twofactor/totp2fa"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+// setupTOTP adds the routes to enroll in TOTP 2FA under /2fa/totp,
+// and to regenerate the recovery codes under /2fa/recovery.
+// Logins of enrolled users are redirected to /2fa/totp/validate.
+func setupTOTP(ab *authboss.Authboss) error {
+	ab.Config.Modules.TOTP2FAIssuer = config.Spec.Auth.TOTP.Issuer
+	if ab.Config.Modules.TOTP2FAIssuer == "" {
+		ab.Config.Modules.TOTP2FAIssuer = "WaaS"
+	}
+
+	if err := (&totp2fa.TOTP{Authboss: ab}).Setup(); err != nil {
+		return err
+	}
+	return (&twofactor.Recovery{Authboss: ab}).Setup()
+}
+
+// HasSecondFactor reports whether the user logged in with
+// a second factor, which admins need when it's required.
+func HasSecondFactor(r *http.Request, user *model.User) bool {
+	return user.GetTOTPSecretKey() != "" && authboss.IsTwoFactored(r)
+}
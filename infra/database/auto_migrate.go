@@ -10,8 +10,20 @@ import (
 func AutoMigrate(db *gorm.DB) {
 	db.AutoMigrate(&model.Device{})
 	db.AutoMigrate(&model.User{})
+	db.AutoMigrate(&model.IPAllocation{})
+	db.AutoMigrate(&model.IPReservation{})
+	db.AutoMigrate(&model.PeerRule{})
+	db.AutoMigrate(&model.APIToken{})
+
+	// Insert an user to the database, once
+	var count int64
+	if err := db.Model(&model.User{}).Where("lower(email) = lower(?)", config.Spec.AdminUserName).Count(&count).Error; err != nil {
+		panic(err)
+	}
+	if count > 0 {
+		return
+	}
 
-	// Insert an user to the database
 	u := new(model.User)
 	u.Username = config.Spec.AdminUserName
 	u.Email = config.Spec.AdminUserName
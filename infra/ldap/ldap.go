@@ -0,0 +1,218 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+)
+
+var (
+	ErrNotFound           = errors.New("user not found in the directory")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Entry is a user in the directory.
+type Entry struct {
+	DN    string
+	Email string
+	Name  string
+	// Groups are the common names of the groups
+	// the user is a member of
+	Groups []string
+}
+
+// Directory looks up and authenticates users in an LDAP
+// server or Active Directory.
+type Directory struct {
+	url                string
+	bindDN             string
+	bindPassword       string
+	baseDN             string
+	userFilter         string
+	emailAttribute     string
+	nameAttribute      string
+	groupAttribute     string
+	startTLS           bool
+	insecureSkipVerify bool
+}
+
+// New returns the directory configured in config.Spec.Auth.LDAP.
+func New() *Directory {
+	cfg := config.Spec.Auth.LDAP
+	d := &Directory{
+		url:                cfg.URL,
+		bindDN:             cfg.BindDN,
+		bindPassword:       cfg.BindPassword,
+		baseDN:             cfg.BaseDN,
+		userFilter:         cfg.UserFilter,
+		emailAttribute:     cfg.EmailAttribute,
+		nameAttribute:      cfg.NameAttribute,
+		groupAttribute:     cfg.GroupAttribute,
+		startTLS:           cfg.StartTLS,
+		insecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if d.userFilter == "" {
+		d.userFilter = "(&(objectClass=person)(mail=%s))"
+	}
+	if d.emailAttribute == "" {
+		d.emailAttribute = "mail"
+	}
+	if d.nameAttribute == "" {
+		d.nameAttribute = "cn"
+	}
+	if d.groupAttribute == "" {
+		d.groupAttribute = "memberOf"
+	}
+	return d
+}
+
+// connect opens a connection bound as the service account.
+func (d *Directory) connect() (*ldap.Conn, error) {
+	u, err := url.Parse(d.url)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ldap url")
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: d.insecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	conn, err := ldap.DialURL(d.url, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to ldap")
+	}
+
+	if d.startTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "failed to start tls")
+		}
+	}
+
+	if d.bindDN != "" {
+		if err := conn.Bind(d.bindDN, d.bindPassword); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "failed to bind to ldap")
+		}
+	}
+	return conn, nil
+}
+
+// Authenticate checks the password of the user with the login, the
+// value used in the user filter, by binding as the user.
+func (d *Directory) Authenticate(login string, password string) (*Entry, error) {
+	// an empty password would be an unauthenticated bind
+	// which most servers accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.find(conn, fmt.Sprintf(d.userFilter, ldap.EscapeFilter(login)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "failed to bind as the user")
+	}
+	return entry, nil
+}
+
+// Lookup finds the user with the login, the value used in the user filter.
+func (d *Directory) Lookup(login string) (*Entry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return d.find(conn, fmt.Sprintf(d.userFilter, ldap.EscapeFilter(login)))
+}
+
+// LookupEmails finds the users with the emails. Users that aren't in
+// the directory are missing from the result.
+func (d *Directory) LookupEmails(emails []string) (map[string]*Entry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries := map[string]*Entry{}
+	for _, email := range emails {
+		filter := fmt.Sprintf("(&(objectClass=person)(%s=%s))", d.emailAttribute, ldap.EscapeFilter(email))
+		entry, err := d.find(conn, filter)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[email] = entry
+	}
+	return entries, nil
+}
+
+func (d *Directory) find(conn *ldap.Conn, filter string) (*Entry, error) {
+	req := ldap.NewSearchRequest(
+		d.baseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, 0, false,
+		filter,
+		[]string{d.emailAttribute, d.nameAttribute, d.groupAttribute},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, errors.Wrap(err, "failed to search ldap")
+	}
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, ErrNotFound
+	case len(res.Entries) > 1:
+		return nil, fmt.Errorf("ldap search %s matched more than one user", filter)
+	}
+
+	result := res.Entries[0]
+	entry := &Entry{
+		DN:    result.DN,
+		Email: result.GetAttributeValue(d.emailAttribute),
+		Name:  result.GetAttributeValue(d.nameAttribute),
+	}
+	for _, group := range result.GetAttributeValues(d.groupAttribute) {
+		entry.Groups = append(entry.Groups, groupName(group))
+	}
+	return entry, nil
+}
+
+// groupName returns the common name of a group DN
+// such as cn=vpn-admins,ou=groups,dc=example,dc=com
+func groupName(group string) string {
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return group
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			return attr.Value
+		}
+	}
+	return group
+}
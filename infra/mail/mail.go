@@ -0,0 +1,115 @@
+// Package mail sends the emails of the auth flows over SMTP, rendered
+// from templates that can be replaced with config.Spec.Mail.Templates.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/pkg/errors"
+	"github.com/volatiletech/authboss/v3"
+	"github.com/volatiletech/authboss/v3/defaults"
+	"github.com/waas-app/WaaS/config"
+)
+
+//go:embed templates
+var builtin embed.FS
+
+// Enabled reports whether an SMTP server is configured.
+func Enabled() bool {
+	return config.Spec.Mail.SMTP != ""
+}
+
+// NewMailer returns a mailer for the configured SMTP server.
+func NewMailer() (authboss.Mailer, error) {
+	cfg := config.Spec.Mail
+	host, _, err := net.SplitHostPort(cfg.SMTP)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid smtp server address")
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return defaults.NewSMTPMailer(cfg.SMTP, auth), nil
+}
+
+// Renderer renders the email templates, name_html is rendered
+// from templates/name.html and name_txt from templates/name.txt.
+type Renderer struct {
+	templates fs.FS
+	html      map[string]*htmltemplate.Template
+	text      map[string]*texttemplate.Template
+}
+
+func NewRenderer() *Renderer {
+	r := &Renderer{
+		html: map[string]*htmltemplate.Template{},
+		text: map[string]*texttemplate.Template{},
+	}
+	r.templates, _ = fs.Sub(builtin, "templates")
+	if dir := config.Spec.Mail.Templates; dir != "" {
+		r.templates = overlay{os.DirFS(dir), r.templates}
+	}
+	return r
+}
+
+func (r *Renderer) Load(names ...string) error {
+	for _, name := range names {
+		switch {
+		case strings.HasSuffix(name, "_html"):
+			file := strings.TrimSuffix(name, "_html") + ".html"
+			t, err := htmltemplate.ParseFS(r.templates, file)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load email template %s", file)
+			}
+			r.html[name] = t
+		case strings.HasSuffix(name, "_txt"):
+			file := strings.TrimSuffix(name, "_txt") + ".txt"
+			t, err := texttemplate.ParseFS(r.templates, file)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load email template %s", file)
+			}
+			r.text[name] = t
+		default:
+			return errors.Errorf("unknown email template %s", name)
+		}
+	}
+	return nil
+}
+
+func (r *Renderer) Render(ctx context.Context, name string, data authboss.HTMLData) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	if t, ok := r.html[name]; ok {
+		err := t.Execute(buf, data)
+		return buf.Bytes(), "text/html", err
+	}
+	if t, ok := r.text[name]; ok {
+		err := t.Execute(buf, data)
+		return buf.Bytes(), "text/plain", err
+	}
+	return nil, "", errors.Errorf("email template %s isn't loaded", name)
+}
+
+// overlay reads files from the first file system that has them.
+type overlay []fs.FS
+
+func (o overlay) Open(name string) (fs.File, error) {
+	var err error
+	for _, fsys := range o {
+		var f fs.File
+		if f, err = fsys.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/red"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+// Throttle limits the requests per client IP with a token bucket
+// stored in redis, see config.Spec.Auth.RateLimit.
+func Throttle(next http.Handler) http.Handler {
+	cfg := config.Spec.Auth.RateLimit
+	if cfg.PerMinute <= 0 {
+		return next
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	proxies := []*net.IPNet{}
+	for _, cidr := range cfg.TrustedProxies {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			util.Logger(context.Background()).Error("Ignoring invalid trusted proxy", zap.String("cidr", cidr), zap.Error(err))
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r, proxies)
+		if ip == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, wait, err := red.Allow(r.Context(), "ratelimit:auth:"+clientKey(ip), cfg.PerMinute, burst)
+		if err != nil {
+			// rather let logins through than lock
+			// everyone out while redis is down
+			util.Logger(r.Context()).Error("Error checking the rate limit", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed {
+			util.Logger(r.Context()).Info("Throttled request", zap.String("ip", ip.String()), zap.String("path", r.URL.Path))
+			seconds := int(wait.Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address the request came from. Behind trusted
+// proxies it's the last address in X-Forwarded-For that isn't a proxy,
+// the addresses before it can be made up by the client.
+func clientIP(r *http.Request, proxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !trusted(ip, proxies) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		next := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if next == nil {
+			break
+		}
+		ip = next
+		if !trusted(ip, proxies) {
+			break
+		}
+	}
+	return ip
+}
+
+func trusted(ip net.IP, proxies []*net.IPNet) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientKey groups IPv6 clients by their /64,
+// which every client usually has to itself.
+func clientKey(ip net.IP) string {
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+	return ip.String()
+}
@@ -1,10 +1,16 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
+	"github.com/volatiletech/authboss/v3"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/helpers/tokens"
 	"github.com/waas-app/WaaS/helpers/users"
 	"github.com/waas-app/WaaS/infra/auth"
+	"github.com/waas-app/WaaS/infra/rbac"
 	"github.com/waas-app/WaaS/model"
 	"github.com/waas-app/WaaS/util"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,8 +20,26 @@ import (
 
 func CheckUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret, ok := bearerToken(r); ok {
+			user, token, err := tokens.Authenticate(r.Context(), secret)
+			if err != nil {
+				util.Logger(r.Context()).Info("Rejected api token", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := users.SetUserInContext(r.Context(), user)
+			ctx = context.WithValue(ctx, config.CurrentAPIToken, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		ab := auth.GetAuthBoss()
 		user, err := ab.CurrentUser(r)
+		if err == authboss.ErrUserNotFound {
+			next.ServeHTTP(w, r)
+			return
+		}
 		if err != nil {
 			util.Logger(r.Context()).Error("Error getting current user", zap.Error(err))
 			next.ServeHTTP(w, r)
@@ -29,6 +53,12 @@ func CheckUser(next http.Handler) http.Handler {
 				span := trace.SpanFromContext(ctx)
 				span.SetAttributes(attribute.Int("user.id", int(u.ID)), attribute.String("user.email", u.GetEmail()), attribute.String("user.pid", u.GetPID()))
 
+				if rbac.Privileged(u) && config.Spec.Auth.TOTP.RequireForAdmins && !auth.HasSecondFactor(r, u) {
+					// admins without a second factor
+					// can still log in to enroll
+					u = rbac.WithoutPrivileges(u)
+				}
+
 				ctx = users.SetUserInContext(ctx, u)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			}
@@ -37,3 +67,11 @@ func CheckUser(next http.Handler) http.Handler {
 		}
 	})
 }
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Rule is what's needed to call a gRPC method.
+type Rule struct {
+	// Permission the user needs, empty when
+	// every user may call the method
+	Permission Permission
+	// Scope API tokens need, tokens can't
+	// call the method when it's empty
+	Scope string
+}
+
+// methods declares the rule of every gRPC method,
+// methods that are missing can't be called.
+var methods = map[string]Rule{
+	"/proto.Server/Info":      {Scope: model.ScopeRead},
+	"/proto.Server/PoolUsage": {Permission: NetworkRead, Scope: model.ScopeRead},
+
+	"/proto.Devices/AddDevice":                 {Scope: model.ScopeDevices},
+	"/proto.Devices/GenerateDevice":            {Scope: model.ScopeDevices},
+	"/proto.Devices/ListSpecificDeviceForUser": {Scope: model.ScopeRead},
+	"/proto.Devices/ListAllDevices":            {Permission: DevicesRead, Scope: model.ScopeRead},
+	"/proto.Devices/DeleteDevice":              {Permission: DevicesManage, Scope: model.ScopeDevices},
+	"/proto.Devices/AddPeerRule":               {Scope: model.ScopeDevices},
+	"/proto.Devices/ListPeerRules":             {Scope: model.ScopeRead},
+	"/proto.Devices/DeletePeerRule":            {Scope: model.ScopeDevices},
+
+	"/proto.DNS/Stats":         {Permission: DNSRead, Scope: model.ScopeRead},
+	"/proto.DNS/SearchQueries": {Permission: DNSQueries, Scope: model.ScopeRead},
+
+	"/proto.Users/ListUsers":       {Permission: UsersRead, Scope: model.ScopeRead},
+	"/proto.Users/CreateUser":      {Permission: UsersManage, Scope: model.ScopeAdmin},
+	"/proto.Users/UpdateUserRoles": {Permission: UsersManage, Scope: model.ScopeAdmin},
+	"/proto.Users/SetUserDisabled": {Permission: UsersManage, Scope: model.ScopeAdmin},
+	"/proto.Users/DeleteUser":      {Permission: UsersManage, Scope: model.ScopeAdmin},
+
+	"/proto.Tokens/CreateToken": {},
+	"/proto.Tokens/ListTokens":  {},
+	"/proto.Tokens/DeleteToken": {},
+}
+
+// UnaryServerInterceptor enforces the rules of the methods.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rule, ok := methods[info.FullMethod]
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "not authorized")
+		}
+
+		user, ok := ctx.Value(config.CurrentUser).(*model.User)
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "not authenticated")
+		}
+
+		if token, ok := ctx.Value(config.CurrentAPIToken).(*model.APIToken); ok {
+			if rule.Scope == "" || !token.Allows(rule.Scope) {
+				return nil, status.Errorf(codes.PermissionDenied, "api token doesn't allow %s", info.FullMethod)
+			}
+		}
+
+		if rule.Permission != "" && !Allowed(user, rule.Permission) {
+			return nil, status.Errorf(codes.PermissionDenied, "not authorized")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,120 @@
+// Package rbac decides what users may do. Users get permissions from
+// their roles, admins have every permission. The permission each gRPC
+// method needs is declared in methods and enforced by the interceptor,
+// handlers only check permissions that depend on the request.
+package rbac
+
+import (
+	"strings"
+
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/model"
+)
+
+type Permission string
+
+const (
+	// DevicesRead lists the devices and peer rules of all users
+	DevicesRead Permission = "devices:read"
+	// DevicesManage deletes devices, chooses device addresses
+	// and links devices of other users
+	DevicesManage Permission = "devices:manage"
+	// NetworkRead shows the address pool usage
+	NetworkRead Permission = "network:read"
+	// DNSRead shows the DNS server stats
+	DNSRead Permission = "dns:read"
+	// DNSQueries searches the DNS query log
+	DNSQueries Permission = "dns:queries"
+	// UsersRead lists the users
+	UsersRead Permission = "users:read"
+	// UsersManage creates, promotes, disables and deletes
+	// users. No role has it since it can make users admins.
+	UsersManage Permission = "users:manage"
+)
+
+var allPermissions = []Permission{DevicesRead, DevicesManage, NetworkRead, DNSRead, DNSQueries, UsersRead, UsersManage}
+
+// Roles and their permissions.
+var Roles = map[string][]Permission{
+	"viewer":         {DevicesRead, NetworkRead, DNSRead, UsersRead},
+	"device-manager": {DevicesRead, DevicesManage, UsersRead},
+	"network-admin":  {DevicesRead, DevicesManage, NetworkRead, DNSRead, UsersRead},
+	"auditor":        {DevicesRead, NetworkRead, DNSRead, DNSQueries, UsersRead},
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	_, ok := Roles[role]
+	return ok
+}
+
+// Allowed reports whether the user has the permission.
+func Allowed(user *model.User, permission Permission) bool {
+	if user.Admin {
+		return true
+	}
+	for _, role := range user.Roles {
+		for _, p := range Roles[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Permissions returns all permissions of the user.
+func Permissions(user *model.User) []Permission {
+	permissions := []Permission{}
+	for _, p := range allPermissions {
+		if Allowed(user, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// Privileged reports whether the user is an admin or has a role.
+func Privileged(user *model.User) bool {
+	return user.Admin || len(user.Roles) > 0
+}
+
+// WithoutPrivileges returns a copy of the user that's
+// neither an admin nor has any roles.
+func WithoutPrivileges(user *model.User) *model.User {
+	u := *user
+	u.Admin = false
+	u.Roles = nil
+	return &u
+}
+
+// RolesForGroups returns the roles config.Spec.Auth.GroupRoles gives
+// to members of the groups. The second value is false when roles
+// aren't managed with groups.
+func RolesForGroups(groups []string) ([]string, bool) {
+	if len(config.Spec.Auth.GroupRoles) == 0 {
+		return nil, false
+	}
+
+	roles := []string{}
+	for group, role := range config.Spec.Auth.GroupRoles {
+		if !ValidRole(role) {
+			continue
+		}
+		for _, g := range groups {
+			if strings.EqualFold(g, group) && !contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
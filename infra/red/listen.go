@@ -0,0 +1,64 @@
+package red
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+// Listen calls fn for every message published on the topics until ctx is
+// done. Unlike PubsubClient.Subscribe no lock is taken, so every listening
+// process gets every message. Use it to keep in-memory state in sync.
+func Listen(ctx context.Context, fn MessageHandlerFunc, topics ...string) error {
+	client, err := GetClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := client.Subscribe(ctx, topics...)
+	go func() {
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				message, err := readMessage(ctx, client, msg)
+				if err != nil {
+					util.Logger(ctx).Error("Error reading message", zap.Error(err), zap.String("channel", msg.Channel))
+					continue
+				}
+				if err := fn(ctx, message); err != nil {
+					util.Logger(ctx).Error("error running the listener function", zap.String("topic", message.Topic), zap.String("listener", fn.GetName()), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// readMessage loads the message a published payload points to.
+func readMessage(ctx context.Context, client *redis.Client, msg *redis.Message) (*Message, error) {
+	var pl payload
+	if err := json.Unmarshal([]byte(msg.Payload), &pl); err != nil {
+		return nil, err
+	}
+
+	val, err := client.Get(ctx, getValStr(pl.Topic, pl.Key)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	message := new(Message)
+	if err := json.Unmarshal([]byte(val), message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
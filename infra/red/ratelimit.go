@@ -0,0 +1,55 @@
+package red
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// tokenBucket refills the bucket by the time passed since it was last
+// used, then takes a token. It uses the redis clock so that the
+// replicas agree on the time. Returns whether a token was taken and
+// the milliseconds until the next one otherwise.
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = time[1] * 1000 + math.floor(time[2] / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+return {allowed, wait}
+`)
+
+// Allow takes a token from the bucket with the key, which holds up to
+// burst tokens and gets perMinute new ones per minute. When the bucket
+// is empty it returns false and how long until a token is available.
+func Allow(ctx context.Context, key string, perMinute int, burst int) (bool, time.Duration, error) {
+	client, err := GetClient(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// tokens per millisecond
+	rate := float64(perMinute) / float64(time.Minute/time.Millisecond)
+	res, err := tokenBucket.Run(ctx, client, []string{key}, rate, burst).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -18,7 +18,7 @@ func getNewClient(ctx context.Context) (*redis.Client, error) {
 	connectionStr := config.Spec.Redis
 	options, err := redis.ParseURL(connectionStr)
 	if err != nil {
-		util.Logger(context.TODO()).Fatal("Failed to Parse Redis Connection String", zap.Error(err))
+		util.Logger(context.TODO()).Error("Failed to Parse Redis Connection String", zap.Error(err))
 		return nil, err
 	}
 	client := redis.NewClient(options)
@@ -1,129 +1,200 @@
 package ip
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/allegro/bigcache/v3"
 	"github.com/miekg/dns"
 	"github.com/waas-app/WaaS/util"
 	"go.uber.org/zap"
 )
 
 type DNSServer struct {
-	server   *dns.Server
-	client   *dns.Client
-	cache    *bigcache.BigCache
-	upstream []string
+	// a udp and a tcp server for every address
+	servers   []*dns.Server
+	cache     *cache
+	upstreams []*upstream
+	// sorted by length so the longest match is found first
+	forwardZones []*forwardZone
+	// number of upstreams queried in parallel
+	parallel int
+	zone     *Zone
+	filter   *Filter
+	queryLog *QueryLog
 }
 
-func New(ctx context.Context, upstream []string) (*DNSServer, error) {
+type DNSOptions struct {
+	// Addresses to listen on over UDP and TCP,
+	// defaults to 0.0.0.0:53
+	Addresses []string
+	Upstream  []string
+	// ForwardZones maps domains to their own upstreams,
+	// e.g. corp.example.com to an on-prem resolver
+	ForwardZones map[string][]string
+	// Race is the number of upstreams queried in parallel,
+	// the rest are only used for failover.
+	Race int
+	// Zone is answered locally instead of being forwarded, optional
+	Zone *Zone
+	// Filter blocks domains per client, optional
+	Filter *Filter
+	// QueryLog records the handled queries, optional
+	QueryLog *QueryLog
+	// CacheSize is the maximum number of cached responses
+	CacheSize int
+	// Prefetch refreshes popular cache entries before they expire
+	Prefetch bool
+}
+
+// New starts a DNS server forwarding to the upstreams.
+func New(ctx context.Context, opts DNSOptions) (*DNSServer, error) {
+	upstream := opts.Upstream
 	if len(upstream) == 0 {
 		upstream = append(upstream, "1.1.1.1")
 	}
 
-	localDNSAddr := "0.0.0.0:53"
-	util.Logger(ctx).Info("Starting DNS server on", zap.String("address", localDNSAddr), zap.Strings(" with upstream", upstream))
-
-	cache, err := bigcache.New(ctx, bigcache.DefaultConfig(10*time.Minute))
-	if err != nil {
-		util.Logger(ctx).Error("Failed to create cache", zap.Error(err))
-		return nil, err
+	addresses := opts.Addresses
+	if len(addresses) == 0 {
+		addresses = append(addresses, "0.0.0.0:53")
 	}
 
+	util.Logger(ctx).Info("Starting DNS server on", zap.Strings("addresses", addresses), zap.Strings(" with upstream", upstream))
+
 	dnsServer := &DNSServer{
-		server: &dns.Server{
-			Addr: localDNSAddr,
-			Net:  "udp",
-		},
-		client: &dns.Client{
-			SingleInflight: true,
-			Timeout:        5 * time.Second,
-		},
-		cache:    cache,
-		upstream: upstream,
+		cache:    newCache(opts.CacheSize, opts.Prefetch),
+		parallel: opts.Race,
+		zone:     opts.Zone,
+		filter:   opts.Filter,
+		queryLog: opts.QueryLog,
+	}
+	for _, address := range upstream {
+		u, err := newUpstream(address, "")
+		if err != nil {
+			util.Logger(ctx).Error("Invalid DNS upstream", zap.Error(err))
+			return nil, err
+		}
+		dnsServer.upstreams = append(dnsServer.upstreams, u)
+	}
+
+	for name, addresses := range opts.ForwardZones {
+		if len(addresses) == 0 {
+			return nil, fmt.Errorf("dns forward zone '%s' has no upstream", name)
+		}
+		zone := &forwardZone{name: dns.Fqdn(strings.ToLower(name))}
+		for _, address := range addresses {
+			u, err := newUpstream(address, zone.name)
+			if err != nil {
+				util.Logger(ctx).Error("Invalid DNS upstream", zap.String("zone", name), zap.Error(err))
+				return nil, err
+			}
+			zone.upstreams = append(zone.upstreams, u)
+		}
+		dnsServer.forwardZones = append(dnsServer.forwardZones, zone)
+	}
+	sort.Slice(dnsServer.forwardZones, func(i, j int) bool {
+		return dns.CountLabel(dnsServer.forwardZones[i].name) > dns.CountLabel(dnsServer.forwardZones[j].name)
+	})
+
+	// listen before returning so that address
+	// errors are reported to the caller
+	for _, address := range addresses {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			dnsServer.Close()
+			return nil, err
+		}
+		dnsServer.serve(ctx, &dns.Server{PacketConn: conn, Handler: dnsServer})
+
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			dnsServer.Close()
+			return nil, err
+		}
+		dnsServer.serve(ctx, &dns.Server{Listener: listener, Handler: dnsServer})
 	}
 
-	dnsServer.server.Handler = dnsServer
+	return dnsServer, nil
+}
 
+func (d *DNSServer) serve(ctx context.Context, server *dns.Server) {
+	d.servers = append(d.servers, server)
 	go func() {
-		if err := dnsServer.server.ListenAndServe(); err != nil {
+		if err := server.ActivateAndServe(); err != nil {
 			util.Logger(ctx).Error("Failed to start DNS server", zap.Error(err))
 		}
 	}()
-
-	return dnsServer, nil
 }
 
 func makekey(m *dns.Msg) string {
 	q := m.Question[0]
-	return fmt.Sprintf("%s:%d:%d", q.Name, q.Qtype, q.Qclass)
-}
-
-func serialize(value interface{}) ([]byte, error) {
-	buf := bytes.Buffer{}
-	enc := gob.NewEncoder(&buf)
-	gob.Register(value)
-
-	err := enc.Encode(&value)
-	if err != nil {
-		return nil, err
+	// DNSSEC records are only included when asked for
+	do := false
+	if opt := m.IsEdns0(); opt != nil {
+		do = opt.Do()
 	}
-
-	return buf.Bytes(), nil
+	return fmt.Sprintf("%s:%d:%d:%t", strings.ToLower(q.Name), q.Qtype, q.Qclass, do)
 }
 
-func deserialize(valueBytes []byte) (interface{}, error) {
-	var value interface{}
-	buf := bytes.NewBuffer(valueBytes)
-	dec := gob.NewDecoder(buf)
+func (d *DNSServer) Lookup(m *dns.Msg) (*dns.Msg, error) {
+	response, _, err := d.lookup(m)
+	return response, err
+}
 
-	err := dec.Decode(&value)
-	if err != nil {
-		return nil, err
+// lookup answers a query from the zone, the cache or the upstreams.
+// cached reports whether the answer came from the cache.
+func (d *DNSServer) lookup(m *dns.Msg) (response *dns.Msg, cached bool, err error) {
+	if d.zone != nil {
+		if response, ok := d.zone.Answer(m); ok {
+			return response, false, nil
+		}
 	}
 
-	return value, nil
-}
-
-func (d *DNSServer) Lookup(m *dns.Msg) (*dns.Msg, error) {
 	key := makekey(m)
 
 	// check the cache first
-	if item, found := d.cache.Get(key); found == nil {
-		util.Logger(context.Background()).Debug("Found cached dns response", zap.Any("for", m))
-		msg, err := deserialize(item)
-		if err != nil {
-			return nil, err
-		}
-
-		acMsg, ok := msg.(*dns.Msg)
-		if !ok {
-			return nil, fmt.Errorf("failed to cast dns message")
+	if response, prefetch, ok := d.cache.get(key, time.Now()); ok {
+		util.Logger(context.Background()).Debug("Found cached dns response", zap.String("for", key))
+		if prefetch {
+			go d.refresh(key, upstreamQuery(m))
 		}
-		return acMsg, nil
+		return response, true, nil
 	}
 
 	// fallback to upstream exchange
-	response, _, err := d.client.Exchange(m, net.JoinHostPort(d.upstream[0], "53"))
+	response, err = d.resolve(context.Background(), upstreamQuery(m))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	if len(response.Answer) > 0 {
-		util.Logger(context.Background()).Debug("Caching dns response", zap.Any("for", m))
-		b, err := serialize(response)
-		if err != nil {
-			return nil, err
-		}
-		d.cache.Set(key, b)
+	d.cache.set(key, response, time.Now())
+	return response, false, nil
+}
+
+// refresh resolves a cached query again before the entry expires.
+func (d *DNSServer) refresh(key string, m *dns.Msg) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+
+	response, err := d.resolve(ctx, m)
+	if err != nil {
+		util.Logger(ctx).Debug("Failed to prefetch dns response", zap.String("for", key), zap.Error(err))
+		return
 	}
+	d.cache.set(key, response, time.Now())
+}
+
+// QueryLog returns the query log, nil when it's disabled.
+func (d *DNSServer) QueryLog() *QueryLog {
+	return d.queryLog
+}
 
-	return response, nil
+// CacheStats returns the hit and miss counts of the cache.
+func (d *DNSServer) CacheStats() *CacheStats {
+	return d.cache.stats()
 }
 
 func (d *DNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
@@ -138,14 +209,40 @@ func (d *DNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 
 	switch r.Opcode {
 	case dns.OpcodeQuery:
-		m, err := d.Lookup(r)
+		start := time.Now()
+		client := remoteIP(w.RemoteAddr())
+		q := &Query{
+			Time: start,
+			Name: strings.ToLower(r.Question[0].Name),
+			Type: dns.TypeToString[r.Question[0].Qtype],
+		}
+		defer func() {
+			if d.queryLog != nil && q.Rcode != "" {
+				q.Latency = time.Since(start)
+				d.queryLog.Record(client, q)
+			}
+		}()
+
+		if d.filter != nil {
+			if m, blocked := d.filter.Answer(client, r); blocked {
+				util.Logger(context.Background()).Debug("Blocked DNS request", zap.String("from", w.RemoteAddr().String()), zap.String("name", r.Question[0].Name))
+				q.Blocked = true
+				q.Rcode = dns.RcodeToString[m.Rcode]
+				writeReply(w, r, m)
+				return
+			}
+		}
+
+		m, cached, err := d.lookup(r)
 		if err != nil {
 			util.Logger(context.Background()).Error("Failed to lookup DNS request", zap.Error(err))
+			q.Rcode = dns.RcodeToString[dns.RcodeServerFailure]
 			dns.HandleFailed(w, r)
 			return
 		}
-		m.SetReply(r)
-		w.WriteMsg(m)
+		q.Cached = cached
+		q.Rcode = dns.RcodeToString[m.Rcode]
+		writeReply(w, r, m)
 	default:
 		m := &dns.Msg{}
 		m.SetReply(r)
@@ -154,5 +251,11 @@ func (d *DNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 }
 
 func (d *DNSServer) Close() error {
-	return d.server.Shutdown()
+	var err error
+	for _, server := range d.servers {
+		if shutdownErr := server.Shutdown(); shutdownErr != nil {
+			err = shutdownErr
+		}
+	}
+	return err
 }
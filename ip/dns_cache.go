@@ -0,0 +1,203 @@
+package ip
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultCacheSize = 10000
+	// upper bound for the lifetime of cached answers
+	maxCacheTTL = 24 * time.Hour
+	// an entry is prefetched once it was served this many
+	// times and less than prefetchThreshold of its TTL is left
+	prefetchHits      = 3
+	prefetchThreshold = 0.1
+)
+
+// CacheStats describes the usage of the DNS cache.
+type CacheStats struct {
+	Entries    int
+	Hits       uint64
+	Misses     uint64
+	Prefetches uint64
+}
+
+type cacheEntry struct {
+	key      string
+	msg      *dns.Msg
+	stored   time.Time
+	ttl      time.Duration
+	hits     int
+	prefetch bool
+}
+
+// cache is an LRU cache of DNS responses that expires entries by the
+// TTL of their records. Negative answers are cached for the SOA minimum
+// as in RFC 2308.
+type cache struct {
+	size     int
+	prefetch bool
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	hits       uint64
+	misses     uint64
+	prefetches uint64
+}
+
+func newCache(size int, prefetch bool) *cache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &cache{
+		size:     size,
+		prefetch: prefetch,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// get returns a copy of the cached response with its TTLs reduced by the
+// time it spent in the cache. prefetch is true when the caller should
+// refresh the entry in the background.
+func (c *cache) get(key string, now time.Time) (m *dns.Msg, prefetch bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.entries[key]
+	if !found {
+		c.misses++
+		return nil, false, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	age := now.Sub(entry.stored)
+	if age >= entry.ttl {
+		c.remove(el)
+		c.misses++
+		return nil, false, false
+	}
+
+	c.hits++
+	entry.hits++
+	c.lru.MoveToFront(el)
+
+	if c.prefetch && !entry.prefetch && entry.hits >= prefetchHits &&
+		float64(entry.ttl-age) < prefetchThreshold*float64(entry.ttl) {
+		entry.prefetch = true
+		prefetch = true
+		c.prefetches++
+	}
+
+	m = entry.msg.Copy()
+	elapsed := uint32(age / time.Second)
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	return m, prefetch, true
+}
+
+// set caches a response if it may be cached.
+func (c *cache) set(key string, m *dns.Msg, now time.Time) {
+	ttl, ok := cacheTTL(m)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.entries[key]; found {
+		c.remove(el)
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:    key,
+		msg:    m.Copy(),
+		stored: now,
+		ttl:    ttl,
+	})
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+func (c *cache) stats() *CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &CacheStats{
+		Entries:    c.lru.Len(),
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Prefetches: c.prefetches,
+	}
+}
+
+// cacheTTL returns how long a response may be cached: the lowest answer
+// TTL for positive answers and the SOA minimum for NXDOMAIN and NODATA
+// answers. Other responses and zero TTLs aren't cached.
+func cacheTTL(m *dns.Msg) (time.Duration, bool) {
+	if m.Truncated {
+		return 0, false
+	}
+
+	var ttl uint32
+	switch {
+	case m.Rcode == dns.RcodeSuccess && len(m.Answer) > 0:
+		ttl = m.Answer[0].Header().Ttl
+		for _, rr := range m.Answer[1:] {
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+		}
+	case m.Rcode == dns.RcodeSuccess || m.Rcode == dns.RcodeNameError:
+		soa := negativeSOA(m)
+		if soa == nil {
+			return 0, false
+		}
+		ttl = soa.Hdr.Ttl
+		if soa.Minttl < ttl {
+			ttl = soa.Minttl
+		}
+	default:
+		return 0, false
+	}
+
+	if ttl == 0 {
+		return 0, false
+	}
+	d := time.Duration(ttl) * time.Second
+	if d > maxCacheTTL {
+		d = maxCacheTTL
+	}
+	return d, true
+}
+
+func negativeSOA(m *dns.Msg) *dns.SOA {
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa
+		}
+	}
+	return nil
+}
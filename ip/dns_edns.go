@@ -0,0 +1,63 @@
+package ip
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ednsBufferSize is the largest UDP response we send or ask upstreams
+// for, the size recommended to avoid IP fragmentation.
+const ednsBufferSize = 1232
+
+// upstreamQuery copies a client query for forwarding. The client's EDNS0
+// options are replaced with ours so the upstream answer fits any client,
+// only the DNSSEC OK bit is kept.
+func upstreamQuery(r *dns.Msg) *dns.Msg {
+	m := r.Copy()
+	do := false
+	if opt := m.IsEdns0(); opt != nil {
+		do = opt.Do()
+	}
+	m.Extra = withoutOPT(m.Extra)
+	m.SetEdns0(ednsBufferSize, do)
+	return m
+}
+
+// writeReply sends m as the reply to r. EDNS0 is only answered if the
+// client used it and UDP responses are truncated to the buffer size the
+// client supports, setting TC so that it retries over TCP.
+func writeReply(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) error {
+	// SetReply would reset the rcode of negative answers
+	m.Id = r.Id
+	m.Response = true
+	m.Extra = withoutOPT(m.Extra)
+
+	size := dns.MinMsgSize
+	if opt := r.IsEdns0(); opt != nil {
+		if udpSize := int(opt.UDPSize()); udpSize > size {
+			size = udpSize
+		}
+		if size > ednsBufferSize {
+			size = ednsBufferSize
+		}
+		m.SetEdns0(uint16(size), opt.Do())
+	}
+
+	if _, tcp := w.RemoteAddr().(*net.TCPAddr); tcp {
+		size = dns.MaxMsgSize
+	}
+	m.Truncate(size)
+
+	return w.WriteMsg(m)
+}
+
+func withoutOPT(extra []dns.RR) []dns.RR {
+	filtered := make([]dns.RR, 0, len(extra))
+	for _, rr := range extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			filtered = append(filtered, rr)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,241 @@
+package ip
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	filterTTL = 60
+	// how long downloading a list may take
+	filterDownloadTimeout = time.Minute
+)
+
+// DomainList is a set of domains. A domain in the list
+// also matches all of its subdomains.
+type DomainList map[string]struct{}
+
+// Contains reports whether name or one of its parents is in the list.
+func (l DomainList) Contains(name string) bool {
+	name = dns.Fqdn(strings.ToLower(name))
+	for {
+		if _, ok := l[name]; ok {
+			return true
+		}
+		i := strings.Index(name, ".")
+		if i < 0 || i == len(name)-1 {
+			return false
+		}
+		name = name[i+1:]
+	}
+}
+
+// ParseDomainList reads a list in hosts-file format, e.g.
+// "0.0.0.0 ads.example.com", or with one domain per line.
+// Comments start with #.
+func ParseDomainList(r io.Reader) (DomainList, error) {
+	list := DomainList{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		// hosts-file entries start with the address
+		if net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, domain := range fields {
+			domain = strings.ToLower(domain)
+			if isLocalHostname(domain) || net.ParseIP(domain) != nil {
+				continue
+			}
+			if _, ok := dns.IsDomainName(domain); !ok {
+				continue
+			}
+			list[dns.Fqdn(domain)] = struct{}{}
+		}
+	}
+	return list, scanner.Err()
+}
+
+// isLocalHostname reports whether a hosts-file entry
+// names the host itself rather than a domain to block.
+func isLocalHostname(name string) bool {
+	switch name {
+	case "localhost", "localhost.localdomain", "local", "broadcasthost", "0.0.0.0":
+		return true
+	}
+	return strings.HasPrefix(name, "ip6-")
+}
+
+// LoadDomainList reads a list from a file or an http(s) URL.
+func LoadDomainList(ctx context.Context, source string) (DomainList, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ParseDomainList(f)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, filterDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", source, res.Status)
+	}
+	return ParseDomainList(res.Body)
+}
+
+// FilterPolicy is a set of blocklists with allowlists that
+// take precedence over them.
+type FilterPolicy struct {
+	Name  string
+	Block []DomainList
+	Allow []DomainList
+}
+
+// Blocks reports whether the policy blocks name.
+func (p *FilterPolicy) Blocks(name string) bool {
+	for _, list := range p.Allow {
+		if list.Contains(name) {
+			return false
+		}
+	}
+	for _, list := range p.Block {
+		if list.Contains(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter answers queries for blocked domains instead of forwarding them.
+// The policy of a query is picked by the client's address. Policies and
+// clients are swapped in as a whole so reloading never blocks queries
+// for long.
+type Filter struct {
+	// answer 0.0.0.0 / :: instead of NXDOMAIN
+	nullResponse bool
+
+	mu       sync.RWMutex
+	policies map[string]*FilterPolicy
+	// used for clients without a policy
+	fallback string
+	// policy name by client address
+	clients map[string]string
+}
+
+func NewFilter(nullResponse bool) *Filter {
+	return &Filter{
+		nullResponse: nullResponse,
+		policies:     map[string]*FilterPolicy{},
+		clients:      map[string]string{},
+	}
+}
+
+// SetPolicies replaces the policies. fallback names the policy used
+// for clients that aren't mapped to one, it may be empty.
+func (f *Filter) SetPolicies(policies []*FilterPolicy, fallback string) {
+	byName := make(map[string]*FilterPolicy, len(policies))
+	for _, policy := range policies {
+		byName[policy.Name] = policy
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.policies = byName
+	f.fallback = fallback
+}
+
+// SetClients replaces the mapping of client addresses to policy names.
+func (f *Filter) SetClients(clients map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients = clients
+}
+
+func (f *Filter) policy(client net.IP) *FilterPolicy {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	name, ok := "", false
+	if client != nil {
+		name, ok = f.clients[client.String()]
+	}
+	if !ok {
+		name = f.fallback
+	}
+	return f.policies[name]
+}
+
+// Answer returns the answer for a blocked query. ok is false
+// when the query isn't blocked for the client.
+func (f *Filter) Answer(client net.IP, r *dns.Msg) (m *dns.Msg, ok bool) {
+	if len(r.Question) == 0 {
+		return nil, false
+	}
+	q := r.Question[0]
+
+	policy := f.policy(client)
+	if policy == nil || !policy.Blocks(q.Name) {
+		return nil, false
+	}
+
+	m = new(dns.Msg)
+	m.SetReply(r)
+	m.RecursionAvailable = true
+	if !f.nullResponse {
+		m.Rcode = dns.RcodeNameError
+		return m, true
+	}
+
+	header := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: filterTTL}
+	switch q.Qtype {
+	case dns.TypeA:
+		m.Answer = append(m.Answer, &dns.A{Hdr: header, A: net.IPv4zero})
+	case dns.TypeAAAA:
+		m.Answer = append(m.Answer, &dns.AAAA{Hdr: header, AAAA: net.IPv6zero})
+	}
+	return m, true
+}
+
+// remoteIP returns the address a query was sent from.
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
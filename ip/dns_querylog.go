@@ -0,0 +1,172 @@
+package ip
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/waas-app/WaaS/model"
+)
+
+const defaultQueryLogSize = 10000
+
+// Query is a query handled by the DNS server.
+type Query struct {
+	Time    time.Time
+	Client  string
+	Name    string
+	Type    string
+	Rcode   string
+	Latency time.Duration
+	Cached  bool
+	Blocked bool
+	// the VPN device that sent the query,
+	// empty for clients outside the VPN
+	Device     string
+	Owner      string
+	OwnerEmail string
+}
+
+// QuerySearch filters the query log, empty fields match everything.
+type QuerySearch struct {
+	Device string
+	// User is the owner's id or email
+	User string
+	// Domain matches the name and its subdomains
+	Domain string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+func (s *QuerySearch) matches(q *Query) bool {
+	if s.Device != "" && s.Device != q.Device {
+		return false
+	}
+	if s.User != "" && s.User != q.Owner && !strings.EqualFold(s.User, q.OwnerEmail) {
+		return false
+	}
+	if s.Domain != "" {
+		domain := strings.ToLower(strings.TrimSuffix(s.Domain, "."))
+		name := strings.TrimSuffix(q.Name, ".")
+		if name != domain && !strings.HasSuffix(name, "."+domain) {
+			return false
+		}
+	}
+	if !s.From.IsZero() && q.Time.Before(s.From) {
+		return false
+	}
+	if !s.To.IsZero() && q.Time.After(s.To) {
+		return false
+	}
+	return true
+}
+
+// QueryLog keeps the most recent queries in memory.
+type QueryLog struct {
+	mu      sync.RWMutex
+	queries []*Query
+	// index of the next write, the oldest entry once full
+	next int
+	full bool
+	// VPN devices by address for attributing queries
+	devices map[string]*model.Device
+}
+
+func NewQueryLog(size int) *QueryLog {
+	if size <= 0 {
+		size = defaultQueryLogSize
+	}
+	return &QueryLog{
+		queries: make([]*Query, size),
+		devices: map[string]*model.Device{},
+	}
+}
+
+// Record adds a query sent from client, attributing it to a device.
+func (l *QueryLog) Record(client net.IP, q *Query) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if client != nil {
+		q.Client = client.String()
+		if device, ok := l.devices[q.Client]; ok {
+			q.Device = device.Name
+			q.Owner = device.Owner
+			q.OwnerEmail = device.OwnerEmail
+		}
+	}
+
+	l.queries[l.next] = q
+	l.next = (l.next + 1) % len(l.queries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Search returns the matching queries, newest first.
+func (l *QueryLog) Search(search *QuerySearch) []*Query {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.queries)
+	}
+
+	results := []*Query{}
+	for i := 1; i <= count; i++ {
+		q := l.queries[(l.next-i+len(l.queries))%len(l.queries)]
+		if !search.matches(q) {
+			continue
+		}
+		results = append(results, q)
+		if search.Limit > 0 && len(results) >= search.Limit {
+			break
+		}
+	}
+	return results
+}
+
+// Replace swaps the devices used to attribute queries.
+func (l *QueryLog) Replace(devices []*model.Device) {
+	byAddress := map[string]*model.Device{}
+	for _, device := range devices {
+		for _, address := range deviceAddresses(device) {
+			byAddress[address] = device
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.devices = byAddress
+}
+
+func (l *QueryLog) SetDevice(device *model.Device) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, address := range deviceAddresses(device) {
+		l.devices[address] = device
+	}
+}
+
+func (l *QueryLog) RemoveDevice(device *model.Device) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for address, d := range l.devices {
+		if d.PublicKey == device.PublicKey {
+			delete(l.devices, address)
+		}
+	}
+}
+
+func deviceAddresses(device *model.Device) []string {
+	addresses := []string{}
+	for _, address := range device.Address {
+		if addr, _, err := net.ParseCIDR(address); err == nil {
+			addresses = append(addresses, addr.String())
+		}
+	}
+	return addresses
+}
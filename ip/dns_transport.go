@@ -0,0 +1,191 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	upstreamTimeout = 5 * time.Second
+	// DoH responses larger than this are rejected
+	maxDoHResponseSize = 64 * 1024
+)
+
+// Transport sends DNS queries to an upstream resolver.
+type Transport interface {
+	Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error)
+	// Address identifies the upstream in logs and stats
+	Address() string
+}
+
+// NewTransport returns the transport for an upstream entry:
+//
+//	1.1.1.1 or 1.1.1.1:53           plain DNS over UDP, retried over TCP when truncated
+//	tcp://1.1.1.1                   plain DNS over TCP
+//	tls://one.one.one.one:853       DNS-over-TLS
+//	https://cloudflare-dns.com/dns-query  DNS-over-HTTPS
+func NewTransport(upstream string) (Transport, error) {
+	if !strings.Contains(upstream, "://") {
+		return newPlainTransport("udp", withPort(upstream, "53")), nil
+	}
+
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dns upstream '%s': %w", upstream, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid dns upstream '%s': missing host", upstream)
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		return newPlainTransport(u.Scheme, withPort(u.Host, "53")), nil
+	case "tls":
+		return newTLSTransport(withPort(u.Host, "853"), u.Hostname()), nil
+	case "https":
+		return newHTTPSTransport(u.String()), nil
+	default:
+		return nil, fmt.Errorf("unsupported dns upstream scheme '%s'", u.Scheme)
+	}
+}
+
+func withPort(host string, port string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	return host
+}
+
+type plainTransport struct {
+	address string
+	client  *dns.Client
+	tcp     *dns.Client
+}
+
+func newPlainTransport(network string, address string) *plainTransport {
+	return &plainTransport{
+		address: address,
+		client:  &dns.Client{Net: network, Timeout: upstreamTimeout},
+		tcp:     &dns.Client{Net: "tcp", Timeout: upstreamTimeout},
+	}
+}
+
+func (t *plainTransport) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	response, _, err := t.client.ExchangeContext(ctx, m, t.address)
+	if err == nil && response.Truncated && t.client.Net == "udp" {
+		response, _, err = t.tcp.ExchangeContext(ctx, m, t.address)
+	}
+	return response, err
+}
+
+func (t *plainTransport) Address() string {
+	if t.client.Net == "udp" {
+		return t.address
+	}
+	return t.client.Net + "://" + t.address
+}
+
+type tlsTransport struct {
+	address string
+	client  *dns.Client
+}
+
+func newTLSTransport(address string, serverName string) *tlsTransport {
+	return &tlsTransport{
+		address: address,
+		client: &dns.Client{
+			Net:     "tcp-tls",
+			Timeout: upstreamTimeout,
+			TLSConfig: &tls.Config{
+				ServerName: serverName,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}
+}
+
+func (t *tlsTransport) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	response, _, err := t.client.ExchangeContext(ctx, m, t.address)
+	return response, err
+}
+
+func (t *tlsTransport) Address() string {
+	return "tls://" + t.address
+}
+
+// httpsTransport implements RFC 8484 using POST requests.
+type httpsTransport struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPSTransport(url string) *httpsTransport {
+	return &httpsTransport{
+		url: url,
+		client: &http.Client{
+			Timeout: upstreamTimeout,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				ForceAttemptHTTP2: true,
+				TLSClientConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+				},
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func (t *httpsTransport) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	// RFC 8484 recommends an id of 0 so responses can be cached
+	query := m.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", t.url, res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxDoHResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(dns.Msg)
+	if err := response.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", t.url, err)
+	}
+	response.Id = m.Id
+	return response, nil
+}
+
+func (t *httpsTransport) Address() string {
+	return t.url
+}
@@ -0,0 +1,239 @@
+package ip
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/waas-app/WaaS/util"
+)
+
+func TestMain(m *testing.M) {
+	util.InitLogger()
+	os.Exit(m.Run())
+}
+
+var answerIP = net.ParseIP("192.0.2.1")
+
+func answer(r *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   answerIP,
+	})
+	return m
+}
+
+// dohServer is a DoH stand-in answering every query with answerIP.
+func dohServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := new(dns.Msg)
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		packed, err := answer(query).Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dotServer is a DoT stand-in answering every query with answerIP, it
+// uses the certificate of an httptest server which is valid for 127.0.0.1.
+// It returns the upstream address and the certificate to trust.
+func dotServer(t *testing.T) (string, *x509.Certificate) {
+	certs := httptest.NewTLSServer(nil)
+	t.Cleanup(certs.Close)
+
+	cfg := certs.TLS.Clone()
+	cfg.NextProtos = nil
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	srv := &dns.Server{
+		Listener: listener,
+		Net:      "tcp-tls",
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			w.WriteMsg(answer(r))
+		}),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return "tls://" + listener.Addr().String(), certs.Certificate()
+}
+
+func transport(t *testing.T, upstream string, cert *x509.Certificate) Transport {
+	transport, err := NewTransport(upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	if cert != nil {
+		pool.AddCert(cert)
+	}
+	switch tr := transport.(type) {
+	case *tlsTransport:
+		tr.client.TLSConfig.RootCAs = pool
+	case *httpsTransport:
+		tr.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
+	}
+	return transport
+}
+
+func query(name string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	return m
+}
+
+func assertAnswer(t *testing.T, m *dns.Msg, response *dns.Msg) {
+	t.Helper()
+	if response.Id != m.Id {
+		t.Errorf("response id %d doesn't match query id %d", response.Id, m.Id)
+	}
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected one answer, got %v", response.Answer)
+	}
+	if a, ok := response.Answer[0].(*dns.A); !ok || !a.A.Equal(answerIP) {
+		t.Fatalf("unexpected answer %s", response.Answer[0])
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	for upstream, want := range map[string]string{
+		"1.1.1.1":                              "1.1.1.1:53",
+		"1.1.1.1:5353":                         "1.1.1.1:5353",
+		"tcp://1.1.1.1":                        "tcp://1.1.1.1:53",
+		"tls://one.one.one.one":                "tls://one.one.one.one:853",
+		"tls://[2606:4700:4700::1111]":         "tls://[2606:4700:4700::1111]:853",
+		"https://cloudflare-dns.com/dns-query": "https://cloudflare-dns.com/dns-query",
+	} {
+		transport, err := NewTransport(upstream)
+		if err != nil {
+			t.Errorf("%s: %s", upstream, err)
+			continue
+		}
+		if transport.Address() != want {
+			t.Errorf("%s: got address %s, want %s", upstream, transport.Address(), want)
+		}
+	}
+
+	for _, upstream := range []string{"quic://1.1.1.1", "https:///dns-query"} {
+		if _, err := NewTransport(upstream); err == nil {
+			t.Errorf("%s: expected an error", upstream)
+		}
+	}
+}
+
+func TestHTTPSTransport(t *testing.T) {
+	srv := dohServer(t)
+	m := query("example.com")
+	response, err := transport(t, srv.URL+"/dns-query", srv.Certificate()).Exchange(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertAnswer(t, m, response)
+}
+
+func TestTLSTransport(t *testing.T) {
+	upstream, cert := dotServer(t)
+	m := query("example.com")
+	response, err := transport(t, upstream, cert).Exchange(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertAnswer(t, m, response)
+}
+
+func TestTransportsVerifyCertificates(t *testing.T) {
+	srv := dohServer(t)
+	if _, err := transport(t, srv.URL, nil).Exchange(context.Background(), query("example.com")); err == nil {
+		t.Error("expected the DoH upstream's unknown certificate to be rejected")
+	}
+
+	upstream, _ := dotServer(t)
+	if _, err := transport(t, upstream, nil).Exchange(context.Background(), query("example.com")); err == nil {
+		t.Error("expected the DoT upstream's unknown certificate to be rejected")
+	}
+}
+
+func TestResolveFailsOver(t *testing.T) {
+	broken := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	// a closed DoT listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "tls://" + listener.Addr().String()
+	listener.Close()
+
+	doh := dohServer(t)
+	dot, cert := dotServer(t)
+
+	for name, upstreams := range map[string][]*upstream{
+		"doh to dot": {
+			{transport: transport(t, broken.URL, broken.Certificate())},
+			{transport: transport(t, dot, cert)},
+		},
+		"dot to doh": {
+			{transport: transport(t, closed, cert)},
+			{transport: transport(t, doh.URL, doh.Certificate())},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := &DNSServer{upstreams: upstreams}
+			m := query("example.com")
+			response, err := d.resolve(context.Background(), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertAnswer(t, m, response)
+
+			stats := d.Stats()
+			if stats[0].Failures != 1 || stats[0].LastError == "" {
+				t.Errorf("expected the first upstream to record a failure, got %+v", stats[0])
+			}
+			if stats[1].Queries != 1 || stats[1].Failures != 0 {
+				t.Errorf("expected the second upstream to answer, got %+v", stats[1])
+			}
+			if !strings.HasPrefix(stats[1].Address, "tls://") && !strings.HasPrefix(stats[1].Address, "https://") {
+				t.Errorf("unexpected upstream address %s", stats[1].Address)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+)
+
+const (
+	// consecutive failures before an upstream is marked unhealthy
+	upstreamFailureThreshold = 3
+	// how long an unhealthy upstream is only used as a last resort
+	upstreamBackoff = 30 * time.Second
+	// weight of the newest sample in the latency average
+	latencyWeight = 0.2
+)
+
+// UpstreamStats describes the health and latency of an upstream.
+type UpstreamStats struct {
+	Address string
+	// Zone is the forward zone the upstream
+	// serves, empty for the default upstreams
+	Zone       string
+	Healthy    bool
+	Queries    uint64
+	Failures   uint64
+	AvgLatency time.Duration
+	LastError  string
+}
+
+type upstream struct {
+	transport Transport
+	zone      string
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	downUntil           time.Time
+	queries             uint64
+	failures            uint64
+	avgLatency          time.Duration
+	lastError           string
+}
+
+func newUpstream(address string, zone string) (*upstream, error) {
+	transport, err := NewTransport(address)
+	if err != nil {
+		return nil, err
+	}
+	return &upstream{
+		transport: transport,
+		zone:      zone,
+	}, nil
+}
+
+func (u *upstream) healthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return now.After(u.downUntil)
+}
+
+func (u *upstream) success(latency time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.queries++
+	u.consecutiveFailures = 0
+	u.downUntil = time.Time{}
+	if u.avgLatency == 0 {
+		u.avgLatency = latency
+	} else {
+		u.avgLatency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(u.avgLatency))
+	}
+}
+
+func (u *upstream) failure(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.queries++
+	u.failures++
+	u.consecutiveFailures++
+	u.lastError = err.Error()
+	if u.consecutiveFailures >= upstreamFailureThreshold {
+		u.downUntil = time.Now().Add(upstreamBackoff)
+	}
+}
+
+func (u *upstream) stats(now time.Time) *UpstreamStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return &UpstreamStats{
+		Address:    u.transport.Address(),
+		Zone:       u.zone,
+		Healthy:    now.After(u.downUntil),
+		Queries:    u.queries,
+		Failures:   u.failures,
+		AvgLatency: u.avgLatency,
+		LastError:  u.lastError,
+	}
+}
+
+// forwardZone sends the queries for a domain
+// and its subdomains to its own upstreams.
+type forwardZone struct {
+	name      string
+	upstreams []*upstream
+}
+
+// upstreamsFor returns the upstreams of the longest forward
+// zone containing name, or the default upstreams.
+func (d *DNSServer) upstreamsFor(name string) []*upstream {
+	name = strings.ToLower(name)
+	for _, zone := range d.forwardZones {
+		if dns.IsSubDomain(zone.name, name) {
+			return zone.upstreams
+		}
+	}
+	return d.upstreams
+}
+
+// ordered returns the upstreams to try, healthy ones first in the
+// configured order followed by unhealthy ones as a last resort.
+func ordered(upstreams []*upstream) []*upstream {
+	now := time.Now()
+	ordered := make([]*upstream, len(upstreams))
+	copy(ordered, upstreams)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].healthy(now) && !ordered[j].healthy(now)
+	})
+	return ordered
+}
+
+// exchange sends the query to a single upstream. SERVFAIL answers
+// count as failures so the next upstream is tried.
+func (d *DNSServer) exchange(ctx context.Context, u *upstream, m *dns.Msg) (*dns.Msg, error) {
+	start := time.Now()
+	response, err := u.transport.Exchange(ctx, m)
+	if err == nil && response.Rcode == dns.RcodeServerFailure {
+		err = fmt.Errorf("%s answered SERVFAIL", u.transport.Address())
+	}
+
+	if err != nil {
+		// a raced query cancelled by the winner isn't the upstream's fault
+		if response != nil || ctx.Err() == nil {
+			u.failure(err)
+			util.Logger(ctx).Debug("DNS upstream failed", zap.String("upstream", u.transport.Address()), zap.Error(err))
+		}
+		return response, err
+	}
+
+	u.success(time.Since(start))
+	return response, nil
+}
+
+// resolve forwards a query to the upstreams. The first d.parallel upstreams
+// are queried in parallel and the first good answer wins, the remaining
+// upstreams are tried one after the other if they all fail.
+func (d *DNSServer) resolve(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	upstreams := ordered(d.upstreamsFor(m.Question[0].Name))
+
+	race := d.parallel
+	if race < 1 {
+		race = 1
+	}
+	if race > len(upstreams) {
+		race = len(upstreams)
+	}
+
+	response, err := d.race(ctx, upstreams[:race], m)
+	if err == nil {
+		return response, nil
+	}
+
+	for _, u := range upstreams[race:] {
+		res, exchangeErr := d.exchange(ctx, u, m)
+		if exchangeErr == nil {
+			return res, nil
+		}
+		if res != nil {
+			response = res
+		}
+		err = exchangeErr
+	}
+
+	// every upstream failed, pass on a SERVFAIL if we got one
+	if response != nil {
+		return response, nil
+	}
+	return nil, err
+}
+
+func (d *DNSServer) race(ctx context.Context, upstreams []*upstream, m *dns.Msg) (*dns.Msg, error) {
+	if len(upstreams) == 1 {
+		return d.exchange(ctx, upstreams[0], m)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		response *dns.Msg
+		err      error
+	}
+	results := make(chan result, len(upstreams))
+	for _, u := range upstreams {
+		go func(u *upstream) {
+			// messages aren't safe for concurrent use
+			response, err := d.exchange(ctx, u, m.Copy())
+			results <- result{response, err}
+		}(u)
+	}
+
+	var last result
+	for range upstreams {
+		r := <-results
+		if r.err == nil {
+			return r.response, nil
+		}
+		if r.response != nil || last.response == nil {
+			last = r
+		}
+	}
+	return last.response, last.err
+}
+
+// Stats returns the health and latency of every upstream.
+func (d *DNSServer) Stats() []*UpstreamStats {
+	now := time.Now()
+	stats := make([]*UpstreamStats, 0, len(d.upstreams))
+	for _, u := range d.upstreams {
+		stats = append(stats, u.stats(now))
+	}
+	for _, zone := range d.forwardZones {
+		for _, u := range zone.upstreams {
+			stats = append(stats, u.stats(now))
+		}
+	}
+	return stats
+}
@@ -0,0 +1,290 @@
+package ip
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/waas-app/WaaS/model"
+)
+
+const zoneTTL = 60
+
+// Zone answers authoritatively for the VPN devices, e.g.
+// laptop.alice.vpn.internal, and for reverse lookups of
+// addresses in the VPN subnets.
+type Zone struct {
+	origin   string
+	networks []*net.IPNet
+
+	mu      sync.RWMutex
+	names   map[string][]net.IP
+	reverse map[string]string
+	// records by public key so deleted devices can be removed
+	devices map[string]*zoneEntry
+}
+
+type zoneEntry struct {
+	name  string
+	addrs []net.IP
+}
+
+func NewZone(origin string, cidrs []string) *Zone {
+	z := &Zone{
+		origin: dns.Fqdn(strings.ToLower(origin)),
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			z.networks = append(z.networks, network)
+		}
+	}
+	z.Replace(nil)
+	return z
+}
+
+// DeviceName returns the name of a device in the zone.
+func (z *Zone) DeviceName(device *model.Device) string {
+	owner := device.OwnerName
+	if owner == "" {
+		owner = device.OwnerEmail
+	}
+	if i := strings.Index(owner, "@"); i >= 0 {
+		owner = owner[:i]
+	}
+	return label(device.Name) + "." + label(owner) + "." + z.origin
+}
+
+// label turns a name into a valid DNS label.
+func label(name string) string {
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	l := strings.Trim(b.String(), "-")
+	if len(l) > 63 {
+		l = l[:63]
+	}
+	if l == "" {
+		return "unnamed"
+	}
+	return l
+}
+
+// Replace swaps the zone's records for the given devices.
+func (z *Zone) Replace(devices []*model.Device) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.names = map[string][]net.IP{}
+	z.reverse = map[string]string{}
+	z.devices = map[string]*zoneEntry{}
+	for _, device := range devices {
+		z.add(device)
+	}
+}
+
+func (z *Zone) SetDevice(device *model.Device) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.remove(device.PublicKey)
+	z.add(device)
+}
+
+func (z *Zone) RemoveDevice(device *model.Device) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.remove(device.PublicKey)
+}
+
+func (z *Zone) add(device *model.Device) {
+	entry := &zoneEntry{name: z.DeviceName(device)}
+	for _, address := range device.Address {
+		addr, _, err := net.ParseCIDR(address)
+		if err != nil {
+			continue
+		}
+		entry.addrs = append(entry.addrs, addr)
+		z.names[entry.name] = append(z.names[entry.name], addr)
+		if reverse, err := dns.ReverseAddr(addr.String()); err == nil {
+			z.reverse[reverse] = entry.name
+		}
+	}
+	z.devices[device.PublicKey] = entry
+}
+
+func (z *Zone) remove(publicKey string) {
+	entry, ok := z.devices[publicKey]
+	if !ok {
+		return
+	}
+
+	// another device may share the name, only drop our addresses
+	remaining := []net.IP{}
+	for _, addr := range z.names[entry.name] {
+		if !containsIP(entry.addrs, addr) {
+			remaining = append(remaining, addr)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(z.names, entry.name)
+	} else {
+		z.names[entry.name] = remaining
+	}
+
+	for _, addr := range entry.addrs {
+		if reverse, err := dns.ReverseAddr(addr.String()); err == nil && z.reverse[reverse] == entry.name {
+			delete(z.reverse, reverse)
+		}
+	}
+	delete(z.devices, publicKey)
+}
+
+func containsIP(addrs []net.IP, addr net.IP) bool {
+	for _, a := range addrs {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Answer returns an authoritative answer if the query is for a name in
+// the zone or a reverse lookup of a VPN address. ok is false when the
+// query should be forwarded upstream.
+func (z *Zone) Answer(r *dns.Msg) (m *dns.Msg, ok bool) {
+	if len(r.Question) == 0 {
+		return nil, false
+	}
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+
+	switch {
+	case dns.IsSubDomain(z.origin, name):
+		return z.answerName(r, name, q.Qtype), true
+	case z.isVPNReverse(name):
+		return z.answerReverse(r, name, q.Qtype), true
+	default:
+		return nil, false
+	}
+}
+
+func (z *Zone) answerName(r *dns.Msg, name string, qtype uint16) *dns.Msg {
+	z.mu.RLock()
+	addrs, found := z.names[name]
+	z.mu.RUnlock()
+
+	m := z.reply(r)
+	if !found {
+		if name != z.origin {
+			m.Rcode = dns.RcodeNameError
+		}
+		m.Ns = append(m.Ns, z.soa())
+		return m
+	}
+
+	for _, addr := range addrs {
+		header := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: zoneTTL}
+		if v4 := addr.To4(); v4 != nil && (qtype == dns.TypeA || qtype == dns.TypeANY) {
+			header.Rrtype = dns.TypeA
+			m.Answer = append(m.Answer, &dns.A{Hdr: header, A: v4})
+		} else if v4 == nil && (qtype == dns.TypeAAAA || qtype == dns.TypeANY) {
+			header.Rrtype = dns.TypeAAAA
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: header, AAAA: addr})
+		}
+	}
+	if len(m.Answer) == 0 {
+		m.Ns = append(m.Ns, z.soa())
+	}
+	return m
+}
+
+func (z *Zone) answerReverse(r *dns.Msg, name string, qtype uint16) *dns.Msg {
+	z.mu.RLock()
+	target, found := z.reverse[name]
+	z.mu.RUnlock()
+
+	m := z.reply(r)
+	switch {
+	case !found:
+		m.Rcode = dns.RcodeNameError
+		m.Ns = append(m.Ns, z.soa())
+	case qtype == dns.TypePTR || qtype == dns.TypeANY:
+		m.Answer = append(m.Answer, &dns.PTR{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: zoneTTL},
+			Ptr: target,
+		})
+	default:
+		m.Ns = append(m.Ns, z.soa())
+	}
+	return m
+}
+
+// isVPNReverse reports whether name is the reverse lookup
+// name of an address inside one of the VPN subnets.
+func (z *Zone) isVPNReverse(name string) bool {
+	addr := reverseToIP(name)
+	if addr == nil {
+		return false
+	}
+	for _, network := range z.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverseToIP(name string) net.IP {
+	name = strings.TrimSuffix(name, ".")
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa"):
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(labels) != 4 {
+			return nil
+		}
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		return net.ParseIP(strings.Join(labels, ".")).To4()
+	case strings.HasSuffix(name, ".ip6.arpa"):
+		nibbles := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(nibbles) != 32 {
+			return nil
+		}
+		b := strings.Builder{}
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			b.WriteString(nibbles[i])
+			if i%4 == 0 && i > 0 {
+				b.WriteString(":")
+			}
+		}
+		return net.ParseIP(b.String())
+	default:
+		return nil
+	}
+}
+
+func (z *Zone) reply(r *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	m.RecursionAvailable = true
+	return m
+}
+
+func (z *Zone) soa() dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: z.origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: zoneTTL},
+		Ns:      "ns." + z.origin,
+		Mbox:    "hostmaster." + z.origin,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  zoneTTL,
+	}
+}
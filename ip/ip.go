@@ -1,13 +1,12 @@
 package ip
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net"
 
-	"github.com/coreos/go-iptables/iptables"
 	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
 )
 
 func GetWireGuardServerIP(cidr string) *net.IPNet {
@@ -41,41 +40,31 @@ func NextIP(ip net.IP) net.IP {
 	return next
 }
 
-func ConfigureIPTables(ctx context.Context, wgIface string, gatewayIface string, cidr string, allowedIPs []string) error {
-	ipt, err := iptables.New()
-	if err != nil {
-		return err
+// IsIPv6 reports whether a CIDR or plain address is an IPv6 one.
+func IsIPv6(address string) bool {
+	if addr, _, err := net.ParseCIDR(address); err == nil {
+		return addr.To4() == nil
 	}
+	addr := net.ParseIP(address)
+	return addr != nil && addr.To4() == nil
+}
 
-	// Cleanup our chains first so that we don't leak
-	// iptable rules when the network configuration changes.
-	ipt.ClearChain("filter", "WG_ACCESS_SERVER_FORWARD")
-	ipt.ClearChain("nat", "WG_ACCESS_SERVER_POSTROUTING")
-
-	// Create our own chain for forwarding rules
-	ipt.NewChain("filter", "WG_ACCESS_SERVER_FORWARD")
-	ipt.AppendUnique("filter", "FORWARD", "-j", "WG_ACCESS_SERVER_FORWARD")
-
-	// Create our own chain for postrouting rules
-	ipt.NewChain("nat", "WG_ACCESS_SERVER_POSTROUTING")
-	ipt.AppendUnique("nat", "POSTROUTING", "-j", "WG_ACCESS_SERVER_POSTROUTING")
-
-	// Accept client traffic for given allowed ips
-	for _, allowedCIDR := range allowedIPs {
-		if err := ipt.AppendUnique("filter", "WG_ACCESS_SERVER_FORWARD", "-s", cidr, "-d", allowedCIDR, "-j", "ACCEPT"); err != nil {
-			return errors.Wrap(err, "failed to set ip tables rule")
-		}
+// AddInterfaceAddress assigns an extra address to a network interface.
+// wgembed only sets a single address on the WireGuard interface so this
+// is used for the server's IPv6 address.
+func AddInterfaceAddress(iface string, cidr string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return errors.Wrap(err, "failed to find interface")
 	}
 
-	if gatewayIface != "" {
-		if err := ipt.AppendUnique("nat", "WG_ACCESS_SERVER_POSTROUTING", "-s", cidr, "-o", gatewayIface, "-j", "MASQUERADE"); err != nil {
-			return errors.Wrap(err, "failed to set ip tables rule")
-		}
+	addr, err := netlink.ParseAddr(cidr)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse interface ip address")
 	}
 
-	if err := ipt.AppendUnique("filter", "WG_ACCESS_SERVER_FORWARD", "-s", cidr, "-j", "REJECT"); err != nil {
-		return errors.Wrap(err, "failed to set ip tables rule")
+	if err := netlink.AddrReplace(link, addr); err != nil {
+		return errors.Wrap(err, "failed to set interface ip address")
 	}
-
 	return nil
 }
@@ -0,0 +1,290 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/config"
+	"github.com/waas-app/WaaS/infra/database"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// addresses at the start of the subnet that are never
+	// handed out: the network address (x.x.x.0) and the
+	// server's own address (x.x.x.1)
+	firstHostOffset = 2
+
+	// allocations without a device older than this are
+	// considered leaked, e.g. the server crashed between
+	// allocating an address and saving the device.
+	staleAllocationAge = 5 * time.Minute
+)
+
+// Pool allocates device addresses from a VPN subnet. Allocations
+// are stored in the ip_allocations table so they are shared by
+// every server using the same database.
+type Pool struct {
+	network *net.IPNet
+}
+
+// Usage describes how much of a pool is allocated.
+type Usage struct {
+	CIDR string
+	Size uint64
+	Used uint64
+}
+
+func NewPool(cidr string) *Pool {
+	_, network := ip.ParseCIDR(cidr)
+	return &Pool{
+		network: network,
+	}
+}
+
+// ConfiguredPools returns the pools for the configured VPN subnets,
+// the IPv4 pool first followed by the optional IPv6 pool.
+func ConfiguredPools() []*Pool {
+	pools := []*Pool{NewPool(config.Spec.VPN.CIDR)}
+	if config.Spec.VPN.CIDRv6 != "" {
+		pools = append(pools, NewPool(config.Spec.VPN.CIDRv6))
+	}
+	return pools
+}
+
+func (p *Pool) CIDR() string {
+	return p.network.String()
+}
+
+// Allocate assigns an address in the pool to a device and returns it in
+// CIDR notation. A device with a reservation always gets its reserved
+// address, otherwise the next free address is used. Inserting the
+// allocation row is what claims the address; if another server claimed
+// it first the insert does nothing and the next candidate is tried.
+func (p *Pool) Allocate(ctx context.Context, owner string, device string) (string, error) {
+	var allocated string
+	err := database.Instance(ctx).Transaction(func(tx *gorm.DB) error {
+		reservation, err := p.reservation(tx, owner, device)
+		if err != nil {
+			return err
+		}
+
+		if reservation != nil {
+			ok, err := p.claim(tx, net.ParseIP(reservation.Address), owner, device)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("reserved address %s is already in use", reservation.Address)
+			}
+			allocated = p.format(net.ParseIP(reservation.Address))
+			return nil
+		}
+
+		used, err := p.allocatedAddresses(tx)
+		if err != nil {
+			return err
+		}
+
+		reserved, err := p.reservedAddresses(tx)
+		if err != nil {
+			return err
+		}
+
+		for candidate := p.firstHost(); p.network.Contains(candidate); candidate = ip.NextIP(candidate) {
+			if _, ok := used[candidate.String()]; ok {
+				continue
+			}
+			if _, ok := reserved[candidate.String()]; ok {
+				continue
+			}
+
+			ok, err := p.claim(tx, candidate, owner, device)
+			if err != nil {
+				return err
+			}
+			if ok {
+				allocated = p.format(candidate)
+				return nil
+			}
+
+			// claimed by someone else in the meantime
+			used[candidate.String()] = struct{}{}
+		}
+
+		return fmt.Errorf("there are no free IP addresses in the vpn subnet: '%s'", p.network)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	util.Logger(ctx).Debug("Allocated ip address", zap.String("address", allocated), zap.String("owner", owner), zap.String("device", device))
+	return allocated, nil
+}
+
+// claim inserts the allocation row for an address and reports
+// whether it was free.
+func (p *Pool) claim(tx *gorm.DB, addr net.IP, owner string, device string) (bool, error) {
+	allocation := &model.IPAllocation{
+		Address:   addr.String(),
+		Owner:     owner,
+		Device:    device,
+		CreatedAt: time.Now(),
+	}
+
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(allocation)
+	if res.Error != nil {
+		return false, errors.Wrap(res.Error, "failed to save ip allocation")
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// Release frees every address in the pool held by a device.
+func (p *Pool) Release(ctx context.Context, owner string, device string) error {
+	db := database.Instance(ctx)
+	allocations := make([]*model.IPAllocation, 0)
+	if err := db.Where("owner = ? AND device = ?", owner, device).Find(&allocations).Error; err != nil {
+		return errors.Wrap(err, "failed to list ip allocations")
+	}
+
+	for _, allocation := range allocations {
+		if !p.network.Contains(net.ParseIP(allocation.Address)) {
+			continue
+		}
+		if err := db.Delete(allocation).Error; err != nil {
+			util.Logger(ctx).Error("Failed to release ip address", zap.Error(err), zap.String("address", allocation.Address))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Free removes the allocation of a single address given in CIDR
+// notation, addresses outside the pool are ignored.
+func (p *Pool) Free(ctx context.Context, address string) error {
+	addr, _, err := net.ParseCIDR(address)
+	if err != nil || !p.network.Contains(addr) {
+		return nil
+	}
+	if err := database.Instance(ctx).Where("address = ?", addr.String()).Delete(&model.IPAllocation{}).Error; err != nil {
+		return errors.Wrap(err, "failed to release ip address")
+	}
+	return nil
+}
+
+// Usage reports the pool's size and the number of allocated addresses.
+func (p *Pool) Usage(ctx context.Context) (*Usage, error) {
+	used, err := p.allocatedAddresses(database.Instance(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{
+		CIDR: p.CIDR(),
+		Size: p.size(),
+		Used: uint64(len(used)),
+	}, nil
+}
+
+// Reconcile makes the allocation table match the stored devices. Devices
+// created before the table existed get their addresses recorded and
+// leaked allocations for devices that no longer exist are removed.
+func (p *Pool) Reconcile(ctx context.Context, devices []*model.Device) error {
+	db := database.Instance(ctx)
+
+	owned := map[string]bool{}
+	for _, device := range devices {
+		for _, address := range device.Address {
+			addr, _, err := net.ParseCIDR(address)
+			if err != nil || !p.network.Contains(addr) {
+				continue
+			}
+			owned[addr.String()] = true
+
+			allocation := &model.IPAllocation{
+				Address:   addr.String(),
+				Owner:     device.Owner,
+				Device:    device.Name,
+				CreatedAt: device.CreatedAt,
+			}
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(allocation).Error; err != nil {
+				return errors.Wrap(err, "failed to backfill ip allocation")
+			}
+		}
+	}
+
+	allocations := make([]*model.IPAllocation, 0)
+	if err := db.Where("created_at < ?", time.Now().Add(-staleAllocationAge)).Find(&allocations).Error; err != nil {
+		return errors.Wrap(err, "failed to list ip allocations")
+	}
+
+	for _, allocation := range allocations {
+		if owned[allocation.Address] || !p.network.Contains(net.ParseIP(allocation.Address)) {
+			continue
+		}
+		util.Logger(ctx).Info("Removing stale ip allocation", zap.String("address", allocation.Address), zap.String("device", allocation.Device))
+		if err := db.Delete(allocation).Error; err != nil {
+			return errors.Wrap(err, "failed to remove stale ip allocation")
+		}
+	}
+
+	return nil
+}
+
+// allocatedAddresses returns the addresses in this pool allocated to devices.
+func (p *Pool) allocatedAddresses(db *gorm.DB) (map[string]struct{}, error) {
+	addresses := make([]string, 0)
+	if err := db.Model(&model.IPAllocation{}).Pluck("address", &addresses).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list ip allocations")
+	}
+	return p.filter(addresses), nil
+}
+
+// filter returns the set of addresses that are inside the pool.
+func (p *Pool) filter(addresses []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		if addr := net.ParseIP(address); addr != nil && p.network.Contains(addr) {
+			set[addr.String()] = struct{}{}
+		}
+	}
+	return set
+}
+
+func (p *Pool) firstHost() net.IP {
+	addr := p.network.IP.Mask(p.network.Mask)
+	for i := 0; i < firstHostOffset; i++ {
+		addr = ip.NextIP(addr)
+	}
+	return addr
+}
+
+// format returns a single address CIDR, i.e. a /32
+// for IPv4 pools and a /128 for IPv6 pools.
+func (p *Pool) format(addr net.IP) string {
+	_, bits := p.network.Mask.Size()
+	return fmt.Sprintf("%s/%d", addr.String(), bits)
+}
+
+func (p *Pool) size() uint64 {
+	ones, bits := p.network.Mask.Size()
+	hostBits := bits - ones
+	if hostBits >= 64 {
+		return math.MaxUint64
+	}
+	total := uint64(1) << uint(hostBits)
+	if total <= firstHostOffset {
+		return 0
+	}
+	return total - firstHostOffset
+}
@@ -0,0 +1,198 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/infra/database"
+	"github.com/waas-app/WaaS/ip"
+	"github.com/waas-app/WaaS/model"
+	"github.com/waas-app/WaaS/util"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ParseAddress parses a plain address or a single address
+// CIDR such as 10.44.0.20/32.
+func ParseAddress(address string) (net.IP, error) {
+	if addr, network, err := net.ParseCIDR(address); err == nil {
+		if ones, bits := network.Mask.Size(); ones != bits {
+			return nil, fmt.Errorf("'%s' is not a single address", address)
+		}
+		return addr, nil
+	}
+
+	addr := net.ParseIP(address)
+	if addr == nil {
+		return nil, fmt.Errorf("'%s' is not a valid ip address", address)
+	}
+	return addr, nil
+}
+
+func (p *Pool) Contains(addr net.IP) bool {
+	return p.network.Contains(addr)
+}
+
+// Validate checks that an address can be handed out to a device,
+// i.e. it is inside the pool and isn't the network or server address.
+func (p *Pool) Validate(addr net.IP) error {
+	if !p.network.Contains(addr) {
+		return fmt.Errorf("%s is not in the vpn subnet '%s'", addr, p.network)
+	}
+
+	reserved := p.network.IP.Mask(p.network.Mask)
+	for i := 0; i < firstHostOffset; i++ {
+		if reserved.Equal(addr) {
+			return fmt.Errorf("%s is reserved for the vpn server", addr)
+		}
+		reserved = ip.NextIP(reserved)
+	}
+	return nil
+}
+
+// ReservationChange is what Reserve changed, so that it can be undone.
+type ReservationChange struct {
+	// Created is the new reservation, nil when the address
+	// was already reserved for the device
+	Created *model.IPReservation
+	// Replaced is the device's previous reservation in the pool
+	Replaced *model.IPReservation
+}
+
+// Reserve pins an address to a device name. The address must be valid
+// for the pool and not allocated or reserved for any other device.
+// A device can only hold one reservation per pool so an existing
+// reservation for the same device is replaced.
+func (p *Pool) Reserve(ctx context.Context, addr net.IP, owner string, device string) (*ReservationChange, error) {
+	if err := p.Validate(addr); err != nil {
+		return nil, err
+	}
+
+	change := &ReservationChange{}
+	err := database.Instance(ctx).Transaction(func(tx *gorm.DB) error {
+		allocation := new(model.IPAllocation)
+		err := tx.Where("address = ?", addr.String()).Limit(1).Find(allocation).Error
+		if err != nil {
+			return errors.Wrap(err, "failed to read ip allocation")
+		}
+		if allocation.Address != "" && (allocation.Owner != owner || allocation.Device != device) {
+			return fmt.Errorf("%s is already allocated to another device", addr)
+		}
+
+		existing, err := p.reservation(tx, owner, device)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if existing.Address == addr.String() {
+				return nil
+			}
+			if err := tx.Delete(existing).Error; err != nil {
+				return errors.Wrap(err, "failed to replace ip reservation")
+			}
+			change.Replaced = existing
+		}
+
+		reservation := &model.IPReservation{
+			Address:   addr.String(),
+			Owner:     owner,
+			Device:    device,
+			CreatedAt: time.Now(),
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reservation)
+		if res.Error != nil {
+			return errors.Wrap(res.Error, "failed to save ip reservation")
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%s is already reserved for another device", addr)
+		}
+
+		change.Created = reservation
+		util.Logger(ctx).Info("Reserved ip address", zap.String("address", addr.String()), zap.String("owner", owner), zap.String("device", device))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// UndoReservation reverts a change made by Reserve, the new reservation
+// is removed and the one it replaced is restored.
+func UndoReservation(ctx context.Context, change *ReservationChange) error {
+	return database.Instance(ctx).Transaction(func(tx *gorm.DB) error {
+		if change.Created != nil {
+			if err := tx.Delete(change.Created).Error; err != nil {
+				return errors.Wrap(err, "failed to delete ip reservation")
+			}
+		}
+		if change.Replaced != nil {
+			if err := tx.Create(change.Replaced).Error; err != nil {
+				return errors.Wrap(err, "failed to restore ip reservation")
+			}
+		}
+		return nil
+	})
+}
+
+// Unreserve removes the device's reservation in this pool. An address
+// that is currently allocated stays allocated until the device is deleted.
+func (p *Pool) Unreserve(ctx context.Context, owner string, device string) error {
+	db := database.Instance(ctx)
+	reservation, err := p.reservation(db, owner, device)
+	if err != nil {
+		return err
+	}
+	if reservation == nil {
+		return nil
+	}
+
+	if err := db.Delete(reservation).Error; err != nil {
+		return errors.Wrap(err, "failed to delete ip reservation")
+	}
+	return nil
+}
+
+// Reservations lists the reservations in this pool.
+func (p *Pool) Reservations(ctx context.Context) ([]*model.IPReservation, error) {
+	all := make([]*model.IPReservation, 0)
+	if err := database.Instance(ctx).Order("owner, device").Find(&all).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list ip reservations")
+	}
+
+	reservations := make([]*model.IPReservation, 0, len(all))
+	for _, reservation := range all {
+		if p.network.Contains(net.ParseIP(reservation.Address)) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
+// reservation returns the device's reservation in this pool, if any.
+func (p *Pool) reservation(db *gorm.DB, owner string, device string) (*model.IPReservation, error) {
+	reservations := make([]*model.IPReservation, 0)
+	if err := db.Where("owner = ? AND device = ?", owner, device).Find(&reservations).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to read ip reservations")
+	}
+
+	for _, reservation := range reservations {
+		if p.network.Contains(net.ParseIP(reservation.Address)) {
+			return reservation, nil
+		}
+	}
+	return nil, nil
+}
+
+// reservedAddresses returns the addresses in this pool reserved for devices.
+func (p *Pool) reservedAddresses(db *gorm.DB) (map[string]struct{}, error) {
+	addresses := make([]string, 0)
+	if err := db.Model(&model.IPReservation{}).Pluck("address", &addresses).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to list ip reservations")
+	}
+	return p.filter(addresses), nil
+}
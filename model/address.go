@@ -0,0 +1,53 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// AddressList holds the VPN addresses of a device in CIDR notation,
+// e.g. an IPv4 /32 and an IPv6 /128 on dual-stack servers. It is
+// stored in a single column using the WireGuard config syntax so
+// rows written before IPv6 support keep working.
+type AddressList []string
+
+// String joins the addresses the way WireGuard config files expect.
+func (l AddressList) String() string {
+	return strings.Join(l, ", ")
+}
+
+func (l AddressList) Value() (driver.Value, error) {
+	return l.String(), nil
+}
+
+func (l *AddressList) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		raw = ""
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported address list value: %T", value)
+	}
+
+	*l = ParseAddressList(raw)
+	return nil
+}
+
+func (AddressList) GormDataType() string {
+	return "string"
+}
+
+func ParseAddressList(raw string) AddressList {
+	list := AddressList{}
+	for _, address := range strings.Split(raw, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			list = append(list, address)
+		}
+	}
+	return list
+}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// API token scopes, each includes the ones before it.
+const (
+	// ScopeRead only allows calls that don't change anything
+	ScopeRead = "read"
+	// ScopeDevices also allows managing devices and peer rules
+	ScopeDevices = "devices"
+	// ScopeAdmin keeps the owner's admin access and roles
+	ScopeAdmin = "admin"
+)
+
+var scopeLevels = map[string]int{
+	ScopeRead:    1,
+	ScopeDevices: 2,
+	ScopeAdmin:   3,
+}
+
+// ValidScope reports whether scope is a known API token scope.
+func ValidScope(scope string) bool {
+	_, ok := scopeLevels[scope]
+	return ok
+}
+
+// APIToken lets scripts call the API on behalf of a user. Only
+// the SHA-256 hash of the token is stored, it's shown to the user
+// once when it's created.
+type APIToken struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID uint   `json:"user_id" gorm:"index"`
+	Name   string `json:"name"`
+	// Prefix is the start of the token, to tell tokens apart
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-" gorm:"uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+}
+
+func (t *APIToken) TableName() string {
+	return "api_tokens"
+}
+
+func (t *APIToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Allows reports whether the token's scopes include scope.
+func (t *APIToken) Allows(scope string) bool {
+	for _, s := range t.Scopes {
+		if scopeLevels[s] >= scopeLevels[scope] {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/waas-app/WaaS/config"
 	"github.com/waas-app/WaaS/infra/red"
+	"github.com/waas-app/WaaS/util"
 	"gorm.io/gorm"
 )
 
@@ -16,24 +17,50 @@ type DevicePayload struct {
 }
 
 type Device struct {
-	Owner             string     `json:"owner" gorm:"type:varchar(100);unique_index:key;primary_key"`
-	OwnerName         string     `json:"owner_name"`
-	OwnerEmail        string     `json:"owner_email"`
-	Name              string     `json:"name" gorm:"type:varchar(100);unique_index:key;primary_key"`
-	PublicKey         string     `json:"public_key" gorm:"unique_index"`
-	Address           string     `json:"address"`
-	CreatedAt         time.Time  `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt         time.Time  `json:"updated_at" gorm:"column:updated_at"`
-	LastHandshakeTime *time.Time `json:"last_handshake_time"`
-	ReceiveBytes      int64      `json:"received_bytes"`
-	TransmitBytes     int64      `json:"transmit_bytes"`
-	Endpoint          string     `json:"endpoint"`
+	Owner             string      `json:"owner" gorm:"type:varchar(100);unique_index:key;primary_key"`
+	OwnerName         string      `json:"owner_name"`
+	OwnerEmail        string      `json:"owner_email"`
+	Name              string      `json:"name" gorm:"type:varchar(100);unique_index:key;primary_key"`
+	PublicKey         string      `json:"public_key" gorm:"unique_index"`
+	Address           AddressList `json:"address"`
+	CreatedAt         time.Time   `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt         time.Time   `json:"updated_at" gorm:"column:updated_at"`
+	LastHandshakeTime *time.Time  `json:"last_handshake_time"`
+	ReceiveBytes      int64       `json:"received_bytes"`
+	TransmitBytes     int64       `json:"transmit_bytes"`
+	Endpoint          string      `json:"endpoint"`
+	// PresharedKey is stored encrypted, use
+	// GetPresharedKey and PutPresharedKey to access it.
+	PresharedKey string `json:"preshared_key,omitempty"`
 }
 
 func (d *Device) TableName() string {
 	return "devices"
 }
 
+// GetPresharedKey returns the decrypted preshared key or
+// an empty string if the device doesn't have one.
+func (d *Device) GetPresharedKey() (string, error) {
+	if d.PresharedKey == "" {
+		return "", nil
+	}
+	return util.Decrypt(d.PresharedKey)
+}
+
+func (d *Device) PutPresharedKey(presharedKey string) error {
+	if presharedKey == "" {
+		d.PresharedKey = ""
+		return nil
+	}
+
+	encrypted, err := util.Encrypt(presharedKey)
+	if err != nil {
+		return err
+	}
+	d.PresharedKey = encrypted
+	return nil
+}
+
 func (d *Device) AfterCreate(tx *gorm.DB) error {
 	ctx := tx.Statement.Context
 	if ctx == nil {
@@ -42,6 +69,7 @@ func (d *Device) AfterCreate(tx *gorm.DB) error {
 
 	payload := new(DevicePayload)
 	payload.Type = config.DevicesCreate
+	payload.Device = d
 
 	p, err := json.Marshal(payload)
 	if err != nil {
@@ -70,6 +98,7 @@ func (d *Device) BeforeDelete(tx *gorm.DB) error {
 
 	payload := new(DevicePayload)
 	payload.Type = config.DevicesDelete
+	payload.Device = d
 
 	p, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// IPAllocation records a VPN address handed out to a device.
+// The address is the primary key so the database guarantees
+// that two devices never get the same address, even when
+// several servers allocate at the same time.
+type IPAllocation struct {
+	Address   string    `json:"address" gorm:"type:varchar(64);primaryKey"`
+	Owner     string    `json:"owner" gorm:"type:varchar(100);index:idx_ip_allocations_device"`
+	Device    string    `json:"device" gorm:"type:varchar(100);index:idx_ip_allocations_device"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (a *IPAllocation) TableName() string {
+	return "ip_allocations"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IPReservation pins a VPN address to a device name. Unlike an
+// IPAllocation it outlives the device, so deleting and recreating
+// a device with the same name gives it the same address again.
+type IPReservation struct {
+	Address   string    `json:"address" gorm:"type:varchar(64);primaryKey"`
+	Owner     string    `json:"owner" gorm:"type:varchar(100);index:idx_ip_reservations_device"`
+	Device    string    `json:"device" gorm:"type:varchar(100);index:idx_ip_reservations_device"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (r *IPReservation) TableName() string {
+	return "ip_reservations"
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// PeerRule lets VPN clients reach each other when client isolation
+// is enabled. A rule either links two devices, in both directions,
+// or links all devices of the same owner when Device and PeerOwner
+// are empty. Rules refer to devices by name so they survive a device
+// being deleted and added again.
+type PeerRule struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Owner      string    `json:"owner" gorm:"type:varchar(100);index"`
+	Device     string    `json:"device" gorm:"type:varchar(100)"`
+	PeerOwner  string    `json:"peer_owner" gorm:"type:varchar(100);index"`
+	PeerDevice string    `json:"peer_device" gorm:"type:varchar(100)"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+func (r *PeerRule) TableName() string {
+	return "peer_rules"
+}
+
+// SameOwner reports whether the rule links all devices of its owner.
+func (r *PeerRule) SameOwner() bool {
+	return r.Device == "" && r.PeerOwner == ""
+}
+
+// Involves reports whether any of the rule's devices belong to owner.
+func (r *PeerRule) Involves(owner string) bool {
+	return r.Owner == owner || r.PeerOwner == owner
+}
@@ -1,6 +1,13 @@
 package model
 
-import "strings"
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waas-app/WaaS/util"
+	"gorm.io/gorm"
+)
 
 type User struct {
 	ID                uint   `gorm:"primary_key;autoIncrement" json:"-"`
@@ -9,6 +16,77 @@ type User struct {
 	Slug              string `gorm:"default:md5((random())::text)" json:"id"`
 	Admin             bool   `json:"admin,omitempty"`
 	EncryptedPassword string `json:"-"`
+	// Groups are used to apply network policies
+	Groups []string `json:"groups,omitempty" gorm:"serializer:json"`
+	// Roles give users some admin permissions,
+	// admins have all of them
+	Roles []string `json:"roles,omitempty" gorm:"serializer:json"`
+	// Provider is the external identity provider that
+	// manages the user, e.g. ldap, empty for local users
+	Provider string `json:"provider,omitempty"`
+	// Disabled users can't log in
+	Disabled bool `json:"disabled,omitempty" gorm:"default:false"`
+	// DisabledByDirectory is set when the user was disabled
+	// for leaving the directory, logging in again only
+	// enables these users and not those an admin disabled
+	DisabledByDirectory bool `json:"-" gorm:"default:false"`
+	// TOTPSecret is the encrypted TOTP secret, it's
+	// decrypted into totpSecretKey when the user is
+	// loaded and encrypted again when it's saved.
+	TOTPSecret    string `json:"-"`
+	TOTPLastCode  string `json:"-"`
+	RecoveryCodes string `json:"-"`
+	// password reset and invite token
+	RecoverSelector    string    `json:"-"`
+	RecoverVerifier    string    `json:"-"`
+	RecoverTokenExpiry time.Time `json:"-"`
+	// users who sign up confirm their email, everyone
+	// else is confirmed
+	Confirmed       bool   `json:"-" gorm:"default:true"`
+	ConfirmSelector string `json:"-"`
+	ConfirmVerifier string `json:"-"`
+	// failed logins, the user is locked
+	// until Locked after too many
+	AttemptCount int       `json:"-"`
+	LastAttempt  time.Time `json:"-"`
+	Locked       time.Time `json:"-"`
+
+	totpSecretKey string
+}
+
+func (u *User) AfterFind(tx *gorm.DB) error {
+	if u.TOTPSecret == "" {
+		u.totpSecretKey = ""
+		return nil
+	}
+	secret, err := util.Decrypt(u.TOTPSecret)
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt totp secret")
+	}
+	u.totpSecretKey = secret
+	return nil
+}
+
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.totpSecretKey == "" {
+		u.TOTPSecret = ""
+		return nil
+	}
+	encrypted, err := util.Encrypt(u.totpSecretKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt totp secret")
+	}
+	u.TOTPSecret = encrypted
+	return nil
+}
+
+func (u *User) InGroup(group string) bool {
+	for _, g := range u.Groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
 }
 
 func (u *User) GetPID() string {
@@ -51,3 +129,52 @@ func (u *User) PutArbitrary(arbitrary map[string]string) {
 func (u *User) PutEmail(email string) { u.Email = email }
 
 func (u *User) GetEmail() string { return u.Email }
+
+func (u *User) GetTOTPSecretKey() string { return u.totpSecretKey }
+
+func (u *User) PutTOTPSecretKey(secret string) { u.totpSecretKey = secret }
+
+func (u *User) GetTOTPLastCode() string { return u.TOTPLastCode }
+
+func (u *User) PutTOTPLastCode(code string) { u.TOTPLastCode = code }
+
+// GetRecoveryCodes returns the bcrypt'd recovery codes as CSV
+func (u *User) GetRecoveryCodes() string { return u.RecoveryCodes }
+
+func (u *User) PutRecoveryCodes(codes string) { u.RecoveryCodes = codes }
+
+func (u *User) GetRecoverSelector() string { return u.RecoverSelector }
+
+func (u *User) PutRecoverSelector(selector string) { u.RecoverSelector = selector }
+
+func (u *User) GetRecoverVerifier() string { return u.RecoverVerifier }
+
+func (u *User) PutRecoverVerifier(verifier string) { u.RecoverVerifier = verifier }
+
+func (u *User) GetRecoverExpiry() time.Time { return u.RecoverTokenExpiry }
+
+func (u *User) PutRecoverExpiry(expiry time.Time) { u.RecoverTokenExpiry = expiry }
+
+func (u *User) GetConfirmed() bool { return u.Confirmed }
+
+func (u *User) PutConfirmed(confirmed bool) { u.Confirmed = confirmed }
+
+func (u *User) GetConfirmSelector() string { return u.ConfirmSelector }
+
+func (u *User) PutConfirmSelector(selector string) { u.ConfirmSelector = selector }
+
+func (u *User) GetConfirmVerifier() string { return u.ConfirmVerifier }
+
+func (u *User) PutConfirmVerifier(verifier string) { u.ConfirmVerifier = verifier }
+
+func (u *User) GetAttemptCount() int { return u.AttemptCount }
+
+func (u *User) PutAttemptCount(attempts int) { u.AttemptCount = attempts }
+
+func (u *User) GetLastAttempt() time.Time { return u.LastAttempt }
+
+func (u *User) PutLastAttempt(last time.Time) { u.LastAttempt = last }
+
+func (u *User) GetLocked() time.Time { return u.Locked }
+
+func (u *User) PutLocked(locked time.Time) { u.Locked = locked }
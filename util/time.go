@@ -15,9 +15,5 @@ func TimeToTimestamp(value *time.Time) *timestamp.Timestamp {
 	if value == nil {
 		return nil
 	}
-	t := timestamppb.New(*value)
-	if t != nil {
-		t = timestamppb.Now()
-	}
-	return t
+	return timestamppb.New(*value)
 }